@@ -0,0 +1,73 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// binaryMagic identifies the binary encoding of a Tree and its version.
+var binaryMagic = [4]byte{'b', 'g', 'i', 1}
+
+// WriteBinary writes the Tree to w in a compact binary format. A four byte header is written
+// followed by the number of stored intervals as a uvarint and then the encoding of each stored
+// interval by enc in sort order. Shared intervals are written in their place in the sort
+// order, so ReadBinary reads them back as intervals with nodes of their own. The format does
+// not depend on the concrete type of the stored intervals, which enc and the decoder passed
+// to ReadBinary must agree on.
+func (t *Tree) WriteBinary(w io.Writer, enc func(Interface, io.Writer) error) error {
+	_, err := w.Write(binaryMagic[:])
+	if err != nil {
+		return err
+	}
+	var buf [binary.MaxVarintLen64]byte
+	_, err = w.Write(buf[:binary.PutUvarint(buf[:], uint64(t.Count))])
+	if err != nil {
+		return err
+	}
+	return t.DoErr(func(e Interface) error { return enc(e, w) })
+}
+
+// ReadBinary returns a Tree read from r in the format written by WriteBinary, decoding each
+// interval with dec. Intervals are decoded one at a time from the stream and the Tree is built
+// from them in O(n) time as by NewFromSorted. If r does not implement io.ByteReader, it is
+// buffered and dec is passed the buffered reader, so r may be read beyond the end of the
+// encoded Tree.
+func ReadBinary(r io.Reader, dec func(io.Reader) (Interface, error)) (*Tree, error) {
+	br, ok := r.(interface {
+		io.Reader
+		io.ByteReader
+	})
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	var magic [4]byte
+	_, err := io.ReadFull(br, magic[:])
+	if err != nil {
+		return nil, err
+	}
+	if magic != binaryMagic {
+		return nil, errors.New("interval: invalid binary header")
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	var elems []Interface
+	for i := uint64(0); i < n; i++ {
+		e, err := dec(br)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		elems = append(elems, e)
+	}
+	return NewFromSorted(elems)
+}
@@ -0,0 +1,82 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+func encodeExported(e Interface, w io.Writer) error {
+	x := e.(*exported)
+	return binary.Write(w, binary.LittleEndian, [3]int64{int64(x.S), int64(x.E), int64(x.Id)})
+}
+
+func decodeExported(r io.Reader) (Interface, error) {
+	var v [3]int64
+	err := binary.Read(r, binary.LittleEndian, &v)
+	if err != nil {
+		return nil, err
+	}
+	return &exported{S: compInt(v[0]), E: compInt(v[1]), Id: uintptr(v[2])}, nil
+}
+
+func (s *S) TestBinary(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&exported{S: s, E: s + compInt(rand.Intn(20)), Id: uintptr(i)}, false)
+	}
+
+	var buf bytes.Buffer
+	c.Assert(t.WriteBinary(&buf, encodeExported), check.Equals, nil)
+	b := buf.Bytes()
+	nt, err := ReadBinary(bytes.NewReader(b), decodeExported)
+	c.Assert(err, check.Equals, nil)
+	c.Check(nt.Len(), check.Equals, t.Len())
+	c.Check(nt.Slice(), check.DeepEquals, t.Slice())
+	c.Check(nt.Validate(), check.Equals, nil)
+
+	_, err = ReadBinary(struct{ io.Reader }{bytes.NewReader(b)}, decodeExported)
+	c.Check(err, check.Equals, nil)
+	_, err = ReadBinary(bytes.NewReader(b[:len(b)-10]), decodeExported)
+	c.Check(err, check.Equals, io.ErrUnexpectedEOF)
+	_, err = ReadBinary(bytes.NewReader([]byte("gob!")), decodeExported)
+	c.Check(err, check.ErrorMatches, "interval: invalid binary header")
+
+	buf.Reset()
+	c.Assert((&Tree{}).WriteBinary(&buf, encodeExported), check.Equals, nil)
+	nt, err = ReadBinary(&buf, decodeExported)
+	c.Assert(err, check.Equals, nil)
+	c.Check(nt.Len(), check.Equals, 0)
+}
+
+func (s *S) TestBinaryShared(c *check.C) {
+	var (
+		t        = &Tree{}
+		equalKey = func(a, b Interface) bool {
+			return a.Start().Compare(b.Start()) == 0 && a.End().Compare(b.End()) == 0
+		}
+	)
+	for i := 0; i < 300; i++ {
+		s := compInt(i % 10)
+		c.Assert(t.InsertShared(&exported{S: s, E: s + 5, Id: uintptr(300 - i)}, equalKey, false), check.Equals, nil)
+	}
+	c.Assert(t.nodes() < t.Len(), check.Equals, true)
+
+	var buf bytes.Buffer
+	c.Assert(t.WriteBinary(&buf, encodeExported), check.Equals, nil)
+	nt, err := ReadBinary(&buf, decodeExported)
+	c.Assert(err, check.Equals, nil)
+	c.Check(nt.Len(), check.Equals, t.Len())
+	c.Check(nt.Slice(), check.DeepEquals, t.Slice())
+	c.Check(nt.Validate(), check.Equals, nil)
+}
@@ -0,0 +1,43 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// NewCompact returns an empty Tree whose nodes hold their ranges as a pair of Comparable
+// values within the node's own allocation rather than in a Mutable obtained from the stored
+// interval's NewMutable method. This halves the number of allocations made for each inserted
+// interval and removes the per-node cost of the Mutable's concrete type. The Range field of
+// each Node remains a Mutable and NewMutable is not used by the Tree's node operations, so
+// stored types must still implement it. Apart from allocation behavior, the returned Tree
+// behaves identically to a zero Tree; trees returned by Clone, Map, Trim and Split do not
+// use the compact layout.
+func NewCompact() *Tree {
+	return &Tree{env: &treeEnv{compact: true}}
+}
+
+// compactRange is a Mutable holding a start and end value.
+type compactRange struct {
+	start, end Comparable
+}
+
+func (r *compactRange) Start() Comparable     { return r.start }
+func (r *compactRange) End() Comparable       { return r.end }
+func (r *compactRange) SetStart(c Comparable) { r.start = c }
+func (r *compactRange) SetEnd(c Comparable)   { r.end = c }
+
+// compactNode is a Node allocated together with the compactRange used as its Range.
+type compactNode struct {
+	Node
+	r compactRange
+}
+
+// newCompactNode returns a Node holding e with its range held in the same allocation.
+func newCompactNode(e Interface) *Node {
+	c := &compactNode{
+		Node: Node{Elem: e, Size: 1},
+		r:    compactRange{start: e.Start(), end: e.End()},
+	}
+	c.Range = &c.r
+	return &c.Node
+}
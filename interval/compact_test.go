@@ -0,0 +1,58 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"testing"
+)
+
+func (s *S) TestNewCompact(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = NewCompact()
+		u          = &Tree{}
+		length     = compInt(10)
+		elems      []Interface
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		e := &overlap{start: s, end: s + compInt(rand.Intn(int(length))), id: uintptr(i)}
+		elems = append(elems, e)
+		t.Insert(e, false)
+		u.Insert(e, false)
+	}
+	for i, e := range elems {
+		if i%3 == 0 {
+			t.Delete(e, false)
+			u.Delete(e, false)
+		}
+	}
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Slice(), check.DeepEquals, u.Slice())
+	for s := compInt(-length); s <= compInt(max)+length; s++ {
+		q := &overlap{start: s, end: s + 1}
+		c.Check(t.Get(q), check.DeepEquals, u.Get(q))
+	}
+
+	t.Rebalance()
+	c.Check(t.Validate(), check.Equals, nil)
+	_, ok := t.Root.Range.(*compactRange)
+	c.Check(ok, check.Equals, true)
+
+	fill := func(t *Tree) {
+		for _, e := range elems[:100] {
+			t.Insert(e, true)
+		}
+	}
+	plain := testing.AllocsPerRun(10, func() { fill(&Tree{}) })
+	compact := testing.AllocsPerRun(10, func() { fill(NewCompact()) })
+	c.Check(compact < plain, check.Equals, true, check.Commentf("compact=%v plain=%v", compact, plain))
+}
+
+func BenchmarkInsertDeleteCompact(b *testing.B) {
+	benchmarkInsertDelete(b, NewCompact())
+}
@@ -0,0 +1,194 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"sync"
+)
+
+// A ConcurrentTree is an interval tree that is safe for concurrent use. Methods that alter
+// the tree hold the write lock and query methods hold the read lock, with traversals holding
+// the read lock until the traversal is complete. Operations may be grouped under a single
+// lock with Update and View. Operations passed to the Do methods must not call methods of
+// the ConcurrentTree.
+//
+// Read-mostly users may avoid read locking by querying a Snapshot of the ConcurrentTree.
+type ConcurrentTree struct {
+	mu   sync.RWMutex
+	tree Tree
+
+	shared bool // Whether the nodes of tree are shared with a snapshot.
+}
+
+// Snapshot returns a frozen Tree holding the intervals stored in the ConcurrentTree at the
+// time of the call. The returned Tree shares its nodes with the ConcurrentTree until the next
+// alteration of the ConcurrentTree, so the snapshot may be queried concurrently with other
+// snapshots and with the ConcurrentTree without locking. Taking a snapshot is O(1), but the
+// first alteration of the ConcurrentTree after a snapshot is taken copies every node of the
+// tree, and so takes O(n) time however small the alteration; writers should batch their
+// alterations with Update when snapshots are taken frequently. Methods of the snapshot that
+// would alter it return ErrFrozen or panic.
+func (t *ConcurrentTree) Snapshot() *Tree {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.shared = true
+	return &Tree{Root: t.tree.Root, Count: t.tree.Count, frozen: true}
+}
+
+// unshare copies the nodes of the tree if they are shared with a snapshot. It must be called
+// with the write lock held before the tree is altered.
+func (t *ConcurrentTree) unshare() {
+	if t.shared {
+		t.tree.Root = t.tree.Root.clone(t.tree.env)
+		t.shared = false
+	}
+}
+
+// Update calls fn with the underlying Tree while holding the write lock, allowing a group of
+// alterations to be performed atomically and, after a Snapshot, at the cost of a single copy
+// of the tree. The Tree must not be retained or used after fn returns, and fn must not call
+// methods of the ConcurrentTree. The error returned by fn is returned.
+func (t *ConcurrentTree) Update(fn func(*Tree) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unshare()
+	return fn(&t.tree)
+}
+
+// View calls fn with the underlying Tree while holding the read lock, allowing a group of
+// queries to see a consistent state of the ConcurrentTree. fn must not alter the Tree, and the
+// Tree must not be retained or used after fn returns.
+func (t *ConcurrentTree) View(fn func(*Tree)) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	fn(&t.tree)
+}
+
+// Len returns the number of intervals stored in the ConcurrentTree.
+func (t *ConcurrentTree) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Len()
+}
+
+// Get returns a slice of Interfaces that overlap q in the ConcurrentTree according
+// to q.Overlap().
+func (t *ConcurrentTree) Get(q Overlapper) []Interface {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Get(q)
+}
+
+// AnyOverlap returns an Interface stored in the ConcurrentTree that overlaps q according
+// to q.Overlap(), and a boolean indicating whether any such interval was found. Errors are
+// returned as for Tree.AnyOverlap.
+func (t *ConcurrentTree) AnyOverlap(q Overlapper) (Interface, bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.AnyOverlap(q)
+}
+
+// Insert inserts the Interface e into the ConcurrentTree.
+func (t *ConcurrentTree) Insert(e Interface, fast bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unshare()
+	return t.tree.Insert(e, fast)
+}
+
+// Delete deletes the element e if it exists in the ConcurrentTree.
+func (t *ConcurrentTree) Delete(e Interface, fast bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unshare()
+	return t.tree.Delete(e, fast)
+}
+
+// DeleteMin deletes the left-most interval.
+func (t *ConcurrentTree) DeleteMin(fast bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unshare()
+	t.tree.DeleteMin(fast)
+}
+
+// DeleteMax deletes the right-most interval.
+func (t *ConcurrentTree) DeleteMax(fast bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unshare()
+	t.tree.DeleteMax(fast)
+}
+
+// AdjustRanges fixes range fields for all Nodes in the ConcurrentTree.
+func (t *ConcurrentTree) AdjustRanges() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.unshare()
+	t.tree.AdjustRanges()
+}
+
+// Min returns the left-most interval stored in the ConcurrentTree.
+func (t *ConcurrentTree) Min() Interface {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Min()
+}
+
+// Max returns the right-most interval stored in the ConcurrentTree.
+func (t *ConcurrentTree) Max() Interface {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Max()
+}
+
+// Floor returns the largest value equal to or less than the query q according to
+// q.Start().Compare(), with ties broken by comparison of ID() values.
+func (t *ConcurrentTree) Floor(q Interface) (Interface, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Floor(q)
+}
+
+// Ceil returns the smallest value equal to or greater than the query q according to
+// q.Start().Compare(), with ties broken by comparison of ID() values.
+func (t *ConcurrentTree) Ceil(q Interface) (Interface, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Ceil(q)
+}
+
+// Do performs fn on all intervals stored in the ConcurrentTree, holding the read lock
+// for the entire traversal.
+func (t *ConcurrentTree) Do(fn Operation) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.Do(fn)
+}
+
+// DoReverse performs fn on all intervals stored in the ConcurrentTree in reverse of sort
+// order, holding the read lock for the entire traversal.
+func (t *ConcurrentTree) DoReverse(fn Operation) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.DoReverse(fn)
+}
+
+// DoMatching performs fn on all intervals stored in the ConcurrentTree that match q
+// according to Overlap, holding the read lock for the entire traversal.
+func (t *ConcurrentTree) DoMatching(fn Operation, q Overlapper) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.DoMatching(fn, q)
+}
+
+// DoMatchingReverse performs fn on all intervals stored in the ConcurrentTree that match
+// q according to Overlap in reverse of sort order, holding the read lock for the entire
+// traversal.
+func (t *ConcurrentTree) DoMatchingReverse(fn Operation, q Overlapper) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tree.DoMatchingReverse(fn, q)
+}
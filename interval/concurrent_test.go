@@ -0,0 +1,126 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"sync"
+)
+
+func (s *S) TestConcurrentTree(c *check.C) {
+	var (
+		max    = 1000
+		t      = &ConcurrentTree{}
+		length = compInt(10)
+		wg     sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < max; i++ {
+			s := compInt(i)
+			t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < max; i++ {
+				s := compInt(i)
+				t.Get(&overlap{start: s, end: s + 1})
+				var n int
+				t.Do(func(Interface) (done bool) { n++; return })
+				if n > max {
+					panic("too many intervals")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Check(t.Len(), check.Equals, max)
+	t.View(func(t *Tree) {
+		c.Check(t.isBST(), check.Equals, true)
+		c.Check(t.is23_234(), check.Equals, true)
+		c.Check(t.isBalanced(), check.Equals, true)
+		c.Check(t.isRanged(), check.Equals, true)
+	})
+
+	for i := 0; i < max; i++ {
+		s := compInt(i)
+		t.Delete(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	c.Check(t.Len(), check.Equals, 0)
+}
+
+func (s *S) TestConcurrentTreeSnapshot(c *check.C) {
+	var (
+		max = 1000
+		t   = &ConcurrentTree{}
+		wg  sync.WaitGroup
+	)
+	for i := 0; i < max; i += 2 {
+		s := compInt(i)
+		t.Insert(&overlap{start: s, end: s + 10, id: uintptr(i)}, false)
+	}
+	snap := t.Snapshot()
+	want := snap.Slice()
+	c.Assert(len(want), check.Equals, max/2)
+	c.Check(snap.Frozen(), check.Equals, true)
+	if debug {
+		c.Check(func() { snap.Insert(&overlap{start: 1, end: 2, id: 1}, false) }, check.PanicMatches, ErrFrozen.Error())
+	} else {
+		c.Check(snap.Insert(&overlap{start: 1, end: 2, id: 1}, false), check.Equals, ErrFrozen)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i < max; i += 2 {
+			s := compInt(i)
+			t.Insert(&overlap{start: s, end: s + 10, id: uintptr(i)}, false)
+		}
+		t.DeleteMin(false)
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < max; i++ {
+				s := compInt(i)
+				if len(snap.Get(&overlap{start: s, end: s + 1})) > 5 {
+					panic("too many intervals")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Check(snap.Len(), check.Equals, max/2)
+	c.Check(snap.Slice(), check.DeepEquals, want)
+	c.Check(snap.isBST(), check.Equals, true)
+	c.Check(snap.isRanged(), check.Equals, true)
+	c.Check(t.Len(), check.Equals, max-1)
+	t.View(func(t *Tree) {
+		c.Check(t.isBST(), check.Equals, true)
+		c.Check(t.isRanged(), check.Equals, true)
+	})
+
+	snap = t.Snapshot()
+	want = snap.Slice()
+	err := t.Update(func(t *Tree) error {
+		for i := 0; i < 10; i++ {
+			if err := t.Insert(&overlap{start: compInt(max + i), end: compInt(max + i + 1), id: uintptr(max + i)}, false); err != nil {
+				return err
+			}
+		}
+		return t.Delete(want[0], false)
+	})
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Len(), check.Equals, max+8)
+	c.Check(snap.Slice(), check.DeepEquals, want)
+	c.Check(snap.isRanged(), check.Equals, true)
+}
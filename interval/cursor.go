@@ -0,0 +1,240 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// A Cursor iterates over the intervals stored in a Tree in sort order, allowing traversal
+// to be interleaved with other work. If the Tree is altered after the Cursor is created or
+// positioned, the behavior of the Cursor is undefined.
+type Cursor struct {
+	t      *Tree
+	stack  []*Node
+	shared []Interface // Shared intervals of the last visited node not yet returned.
+}
+
+// Cursor returns a Cursor positioned before the left-most interval stored in the Tree.
+func (t *Tree) Cursor() *Cursor {
+	c := &Cursor{t: t}
+	c.pushLeft(t.Root)
+	return c
+}
+
+// pushLeft pushes n and its chain of left descendants onto the stack.
+func (c *Cursor) pushLeft(n *Node) {
+	for ; n != nil; n = n.Left {
+		c.stack = append(c.stack, n)
+	}
+}
+
+// Next returns the next interval in sort order and true, or nil and false if the Cursor
+// has passed the right-most interval stored in the Tree.
+func (c *Cursor) Next() (Interface, bool) {
+	if len(c.shared) != 0 {
+		e := c.shared[0]
+		c.shared = c.shared[1:]
+		return e, true
+	}
+	if len(c.stack) == 0 {
+		return nil, false
+	}
+	n := c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	c.pushLeft(n.Right)
+	c.shared = n.Shared
+	return n.Elem, true
+}
+
+// Seek positions the Cursor such that the next call to Next returns the smallest interval
+// equal to or greater than the query q according to q.Start().Compare(), with ties broken
+// by comparison of ID() values.
+func (c *Cursor) Seek(q Interface) {
+	c.stack, c.shared = c.stack[:0], nil
+	m, id := q.Start(), q.ID()
+	var f *Node
+	for n := c.t.Root; n != nil; {
+		switch cmp := m.Compare(n.Elem.Start()); {
+		case cmp < 0 || (cmp == 0 && id <= n.Elem.ID()):
+			c.stack = append(c.stack, n)
+			n = n.Left
+		default:
+			f, n = n, n.Right
+		}
+	}
+	if f != nil {
+		// Shared intervals of the node before q may follow q.
+		c.shared = f.Shared[f.sharedIndex(m, id):]
+	}
+}
+
+// Next returns the interval following the stored interval s in sort order, where s is the
+// stored interval with the same start value as e that satisfies equal(s, e), and true. If no
+// such interval is stored or s is the right-most interval stored in the Tree, nil and false
+// are returned. Next takes O(log n + k) time, where k is the number of stored intervals with
+// the same start value as e. If e is nil, nil and false are returned.
+func (t *Tree) Next(e Interface, equal func(a, b Interface) bool) (Interface, bool) {
+	if e == nil {
+		return nil, false
+	}
+	c, s := t.seekStored(e, equal)
+	if s == nil {
+		return nil, false
+	}
+	return c.Next()
+}
+
+// seekStored returns the stored interval s with the same start value as e that satisfies
+// equal(s, e), and a Cursor positioned after s. If no such interval is stored, s is nil.
+func (t *Tree) seekStored(e Interface, equal func(a, b Interface) bool) (c *Cursor, s Interface) {
+	c = &Cursor{t: t}
+	m := e.Start()
+	for n := t.Root; n != nil; {
+		if m.Compare(n.Elem.Start()) <= 0 {
+			c.stack = append(c.stack, n)
+			n = n.Left
+		} else {
+			n = n.Right
+		}
+	}
+	for {
+		o, ok := c.Next()
+		if !ok || m.Compare(o.Start()) != 0 {
+			return c, nil
+		}
+		if equal(o, e) {
+			return c, o
+		}
+	}
+}
+
+// A ReverseCursor iterates over the intervals stored in a Tree in reverse sort order. If the
+// Tree is altered after the ReverseCursor is created or positioned, the behavior of the
+// ReverseCursor is undefined.
+type ReverseCursor struct {
+	t      *Tree
+	stack  []*Node
+	shared []Interface // Shared intervals of the last visited node not yet returned.
+	elem   Interface   // Elem of the last visited node if not yet returned.
+}
+
+// ReverseCursor returns a ReverseCursor positioned after the right-most interval stored in
+// the Tree.
+func (t *Tree) ReverseCursor() *ReverseCursor {
+	c := &ReverseCursor{t: t}
+	c.pushRight(t.Root)
+	return c
+}
+
+// pushRight pushes n and its chain of right descendants onto the stack.
+func (c *ReverseCursor) pushRight(n *Node) {
+	for ; n != nil; n = n.Right {
+		c.stack = append(c.stack, n)
+	}
+}
+
+// Prev returns the previous interval in sort order and true, or nil and false if the
+// ReverseCursor has passed the left-most interval stored in the Tree.
+func (c *ReverseCursor) Prev() (Interface, bool) {
+	if len(c.shared) != 0 {
+		e := c.shared[len(c.shared)-1]
+		c.shared = c.shared[:len(c.shared)-1]
+		return e, true
+	}
+	if c.elem != nil {
+		e := c.elem
+		c.elem = nil
+		return e, true
+	}
+	if len(c.stack) == 0 {
+		return nil, false
+	}
+	n := c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+	c.pushRight(n.Left)
+	if len(n.Shared) == 0 {
+		return n.Elem, true
+	}
+	c.shared, c.elem = n.Shared[:len(n.Shared)-1], n.Elem
+	return n.Shared[len(n.Shared)-1], true
+}
+
+// SeekReverse positions the ReverseCursor such that the next call to Prev returns the largest
+// interval equal to or less than the query q according to q.Start().Compare(), with ties
+// broken by comparison of ID() values.
+func (c *ReverseCursor) SeekReverse(q Interface) {
+	c.stack, c.shared, c.elem = c.stack[:0], nil, nil
+	m, id := q.Start(), q.ID()
+	for n := c.t.Root; n != nil; {
+		switch cmp := m.Compare(n.Elem.Start()); {
+		case cmp > 0 || (cmp == 0 && id >= n.Elem.ID()):
+			c.stack = append(c.stack, n)
+			n = n.Right
+		default:
+			n = n.Left
+		}
+	}
+	if k := len(c.stack); k != 0 && len(c.stack[k-1].Shared) != 0 {
+		// Shared intervals of the node at or before q may follow q.
+		n := c.stack[k-1]
+		c.stack = c.stack[:k-1]
+		c.pushRight(n.Left)
+		i := n.sharedIndex(m, id)
+		if i < len(n.Shared) && compare(m, id, n.Shared[i]) == 0 {
+			i++
+		}
+		c.shared, c.elem = n.Shared[:i], n.Elem
+	}
+}
+
+// A MatchCursor iterates over the intervals stored in a Tree that overlap a query, in sort
+// order. Subtrees are pruned using node ranges in the same way as DoMatching. If the Tree
+// is altered after the MatchCursor is created, the behavior of the MatchCursor is undefined.
+type MatchCursor struct {
+	q      Overlapper
+	stack  []*Node
+	shared []Interface // Shared intervals of the last matching node not yet returned.
+}
+
+// MatchCursor returns a MatchCursor positioned before the first interval stored in the Tree
+// that overlaps q according to q.Overlap(). If q is nil, the MatchCursor returns no intervals.
+func (t *Tree) MatchCursor(q Overlapper) *MatchCursor {
+	c := &MatchCursor{q: q}
+	if q != nil && t.Root != nil && q.Overlap(t.Root.Range) {
+		c.pushLeft(t.Root)
+	}
+	return c
+}
+
+// pushLeft pushes n and its chain of left descendants with ranges overlapping the query onto
+// the stack. The range of n must overlap the query.
+func (c *MatchCursor) pushLeft(n *Node) {
+	for {
+		c.stack = append(c.stack, n)
+		if n.Left == nil || !c.q.Overlap(n.Left.Range) {
+			return
+		}
+		n = n.Left
+	}
+}
+
+// Next returns the next interval in sort order that overlaps the query and true, or nil and
+// false if there are no more overlapping intervals.
+func (c *MatchCursor) Next() (Interface, bool) {
+	if len(c.shared) != 0 {
+		e := c.shared[0]
+		c.shared = c.shared[1:]
+		return e, true
+	}
+	for len(c.stack) != 0 {
+		n := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		if n.Right != nil && c.q.Overlap(n.Right.Range) {
+			c.pushLeft(n.Right)
+		}
+		if c.q.Overlap(n.Elem) {
+			c.shared = n.Shared
+			return n.Elem, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,159 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+func (s *S) TestCursor(c *check.C) {
+	var (
+		count, max = 1000, 100
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	e, ok := t.Cursor().Next()
+	c.Check(e, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	var elems []Interface
+	t.Do(func(e Interface) (done bool) { elems = append(elems, e); return })
+
+	var got []Interface
+	for cur := t.Cursor(); ; {
+		e, ok := cur.Next()
+		if !ok {
+			break
+		}
+		got = append(got, e)
+	}
+	c.Check(got, check.DeepEquals, elems)
+
+	cur := t.Cursor()
+	for i, e := range elems {
+		cur.Seek(e)
+		end := i + 3
+		if end > len(elems) {
+			end = len(elems)
+		}
+		for _, want := range elems[i:end] {
+			got, ok := cur.Next()
+			c.Check(ok, check.Equals, true)
+			c.Check(got, check.Equals, want)
+		}
+	}
+	cur.Seek(&overlap{start: -1})
+	got0, _ := cur.Next()
+	c.Check(got0, check.Equals, elems[0])
+	cur.Seek(&overlap{start: compInt(max)})
+	_, ok = cur.Next()
+	c.Check(ok, check.Equals, false)
+}
+
+func (s *S) TestReverseCursor(c *check.C) {
+	var (
+		count, max = 1000, 100
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	e, ok := t.ReverseCursor().Prev()
+	c.Check(e, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	var elems []Interface
+	t.DoReverse(func(e Interface) (done bool) { elems = append(elems, e); return })
+
+	var got []Interface
+	for cur := t.ReverseCursor(); ; {
+		e, ok := cur.Prev()
+		if !ok {
+			break
+		}
+		got = append(got, e)
+	}
+	c.Check(got, check.DeepEquals, elems)
+
+	cur := t.ReverseCursor()
+	for i, e := range elems {
+		cur.SeekReverse(e)
+		end := i + 3
+		if end > len(elems) {
+			end = len(elems)
+		}
+		for _, want := range elems[i:end] {
+			got, ok := cur.Prev()
+			c.Check(ok, check.Equals, true)
+			c.Check(got, check.Equals, want)
+		}
+	}
+	cur.SeekReverse(&overlap{start: compInt(max), id: ^uintptr(0)})
+	got0, _ := cur.Prev()
+	c.Check(got0, check.Equals, elems[0])
+	cur.SeekReverse(&overlap{start: -1})
+	_, ok = cur.Prev()
+	c.Check(ok, check.Equals, false)
+}
+
+func (s *S) TestMatchCursor(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	e, ok := t.MatchCursor(&overlap{start: 0, end: 10}).Next()
+	c.Check(e, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	for s := compInt(-length); s <= compInt(max)+length; s += 7 {
+		q := &overlap{start: s, end: s + 3*length}
+		var got []Interface
+		for cur := t.MatchCursor(q); ; {
+			e, ok := cur.Next()
+			if !ok {
+				break
+			}
+			got = append(got, e)
+		}
+		c.Check(got, check.DeepEquals, t.Get(q))
+	}
+}
+
+func (s *S) TestNext(c *check.C) {
+	var (
+		t     = &Tree{}
+		equal = func(a, b Interface) bool { return a.ID() == b.ID() }
+	)
+	e, ok := t.Next(&overlap{start: 0, end: 1}, equal)
+	c.Check(e, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(100))
+		t.Insert(&overlap{start: s, end: s + 10, id: uintptr(i)}, false)
+	}
+	elems := t.Slice()
+	for i, e := range elems {
+		got, ok := t.Next(e, equal)
+		if i == len(elems)-1 {
+			c.Check(got, check.Equals, nil)
+			c.Check(ok, check.Equals, false)
+			continue
+		}
+		c.Check(ok, check.Equals, true)
+		c.Check(got, check.Equals, elems[i+1])
+	}
+	got, ok := t.Next(&overlap{start: 50, end: 60, id: 1000}, equal)
+	c.Check(got, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+}
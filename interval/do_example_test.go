@@ -53,7 +53,7 @@ func Flatten(t *interval.Tree) {
 	)
 
 	mid++
-	t.Root, t.Count = nil, 0
+	t.Clear()
 	for i, iv := range ti {
 		iv.id = uintptr(i) + mid
 		t.Insert(iv, true)
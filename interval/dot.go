@@ -0,0 +1,63 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"code.google.com/p/biogo.store/llrb"
+	"fmt"
+	"io"
+)
+
+// WriteDOT writes a Graphviz DOT representation of the Tree to w. Each node is filled with
+// its color and labeled with the result of label applied to its interval followed by the
+// bounds of the node's range. If label is nil, intervals are formatted with fmt.Sprint.
+// Nodes are named by their pre-order position, so the output for a given tree shape is
+// deterministic.
+func (t *Tree) WriteDOT(w io.Writer, label func(Interface) string) error {
+	if label == nil {
+		label = func(e Interface) string { return fmt.Sprint(e) }
+	}
+	d := dotWriter{w: w, label: label}
+	d.printf("digraph tree {\n\tnode [style=filled,fontcolor=white];\n")
+	if t.Root != nil {
+		d.node(t.Root)
+	}
+	d.printf("}\n")
+	return d.err
+}
+
+// dotWriter holds the state of a WriteDOT call. Write errors are sticky.
+type dotWriter struct {
+	w     io.Writer
+	label func(Interface) string
+	next  int
+	err   error
+}
+
+func (d *dotWriter) printf(format string, args ...interface{}) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = fmt.Fprintf(d.w, format, args...)
+}
+
+// node writes n and its descendants, returning the pre-order index of n.
+func (d *dotWriter) node(n *Node) int {
+	id := d.next
+	d.next++
+	fill := "black"
+	if n.color() == llrb.Red {
+		fill = "red"
+	}
+	d.printf("\tn%d [label=%q,fillcolor=%s];\n",
+		id, fmt.Sprintf("%s\nrange:%v-%v", d.label(n.Elem), n.Range.Start(), n.Range.End()), fill)
+	if n.Left != nil {
+		d.printf("\tn%d -> n%d [label=L];\n", id, d.node(n.Left))
+	}
+	if n.Right != nil {
+		d.printf("\tn%d -> n%d [label=R];\n", id, d.node(n.Right))
+	}
+	return id
+}
@@ -0,0 +1,45 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"bytes"
+	"errors"
+	check "launchpad.net/gocheck"
+	"strings"
+)
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func (s *S) TestWriteDOT(c *check.C) {
+	t := &Tree{}
+	var buf bytes.Buffer
+	c.Check(t.WriteDOT(&buf, nil), check.Equals, nil)
+	c.Check(buf.String(), check.Equals, "digraph tree {\n\tnode [style=filled,fontcolor=white];\n}\n")
+
+	for i := compInt(0); i < 2; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	buf.Reset()
+	c.Check(t.WriteDOT(&buf, nil), check.Equals, nil)
+	c.Check(buf.String(), check.Equals, `digraph tree {
+	node [style=filled,fontcolor=white];
+	n0 [label="[1,6)\nrange:0-6",fillcolor=black];
+	n1 [label="[0,5)\nrange:0-5",fillcolor=red];
+	n0 -> n1 [label=L];
+}
+`)
+
+	for i := compInt(2); i < 4; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	buf.Reset()
+	c.Check(t.WriteDOT(&buf, func(e Interface) string { return "x" }), check.Equals, nil)
+	c.Check(strings.Count(buf.String(), `"x\n`), check.Equals, 4)
+
+	c.Check(t.WriteDOT(failWriter{}, nil), check.ErrorMatches, "write failed")
+}
@@ -0,0 +1,142 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// An EndTree is an interval tree that keeps its intervals sorted by End() rather than by
+// Start(), with node ranges augmented by the minimum start value of each subtree. It is the
+// mirror of Tree and makes end-anchored floor and ceiling queries efficient.
+//
+// Internally an EndTree is a Tree holding the stored intervals with their coordinates
+// reflected, so the stored intervals are visited in ascending end order with intervals
+// sharing an end value visited in descending ID order.
+type EndTree struct {
+	t Tree
+}
+
+// NewByEnd returns a new empty EndTree.
+func NewByEnd() *EndTree { return &EndTree{} }
+
+// reversed is a Comparable that sorts in the reverse order of the Comparable it holds.
+type reversed struct{ c Comparable }
+
+func (r reversed) Compare(b Comparable) int { return b.(reversed).c.Compare(r.c) }
+
+// reflected presents an Interface as the interval [reversed(End), reversed(Start)].
+type reflected struct{ Interface }
+
+func (r reflected) Start() Comparable    { return reversed{r.Interface.End()} }
+func (r reflected) End() Comparable      { return reversed{r.Interface.Start()} }
+func (r reflected) Overlap(b Range) bool { return r.Interface.Overlap(unreflected{b}) }
+func (r reflected) NewMutable() Mutable  { return reflectedRange{r.Interface.NewMutable()} }
+
+// reflectedRange presents a Mutable with its coordinates reflected.
+type reflectedRange struct{ m Mutable }
+
+func (r reflectedRange) Start() Comparable     { return reversed{r.m.End()} }
+func (r reflectedRange) End() Comparable       { return reversed{r.m.Start()} }
+func (r reflectedRange) SetStart(c Comparable) { r.m.SetEnd(c.(reversed).c) }
+func (r reflectedRange) SetEnd(c Comparable)   { r.m.SetStart(c.(reversed).c) }
+
+// unreflected presents a reflected Range in the original coordinates.
+type unreflected struct{ r Range }
+
+func (u unreflected) Start() Comparable { return u.r.End().(reversed).c }
+func (u unreflected) End() Comparable   { return u.r.Start().(reversed).c }
+
+// reflectedQuery presents an Overlapper to the underlying Tree of an EndTree.
+type reflectedQuery struct{ q Overlapper }
+
+func (q reflectedQuery) Overlap(b Range) bool { return q.q.Overlap(unreflected{b}) }
+
+// unreflect returns an Operation that calls fn with the original stored intervals.
+func unreflect(fn Operation) Operation {
+	return func(e Interface) (done bool) { return fn(e.(reflected).Interface) }
+}
+
+// Len returns the number of intervals stored in the EndTree.
+func (t *EndTree) Len() int { return t.t.Len() }
+
+// Insert inserts the Interface e into the EndTree. Insertions may replace existing stored
+// intervals. The fast parameter has the same meaning as for Tree.Insert.
+func (t *EndTree) Insert(e Interface, fast bool) error {
+	if e == nil {
+		return ErrNilOverlapper
+	}
+	return t.t.Insert(reflected{e}, fast)
+}
+
+// Delete deletes the element e if it exists in the EndTree. The fast parameter has the same
+// meaning as for Tree.Delete.
+func (t *EndTree) Delete(e Interface, fast bool) error {
+	if e == nil {
+		return ErrNilOverlapper
+	}
+	return t.t.Delete(reflected{e}, fast)
+}
+
+// AdjustRanges fixes range fields for all Nodes in the EndTree. This must be called before
+// Get or DoMatching* is used if fast insertion or deletion has been performed.
+func (t *EndTree) AdjustRanges() { t.t.AdjustRanges() }
+
+// Min returns the interval with the smallest end value stored in the EndTree.
+func (t *EndTree) Min() Interface {
+	if t.t.Root == nil {
+		return nil
+	}
+	return t.t.Root.max().Elem.(reflected).Interface
+}
+
+// Max returns the interval with the largest end value stored in the EndTree.
+func (t *EndTree) Max() Interface {
+	if t.t.Root == nil {
+		return nil
+	}
+	return t.t.Root.min().Elem.(reflected).Interface
+}
+
+// FloorEnd returns the interval with the largest end value equal to or less than p according
+// to p.Compare(), or nil if no such interval exists.
+func (t *EndTree) FloorEnd(p Comparable) Interface {
+	o, _ := t.t.CeilPoint(reversed{p})
+	if o == nil {
+		return nil
+	}
+	return o.(reflected).Interface
+}
+
+// CeilEnd returns the interval with the smallest end value equal to or greater than p
+// according to p.Compare(), or nil if no such interval exists.
+func (t *EndTree) CeilEnd(p Comparable) Interface {
+	o, _ := t.t.FloorPoint(reversed{p})
+	if o == nil {
+		return nil
+	}
+	return o.(reflected).Interface
+}
+
+// Get returns a slice of Interfaces that overlap q in the EndTree according to q.Overlap(),
+// in end order.
+func (t *EndTree) Get(q Overlapper) (o []Interface) {
+	t.DoMatching(func(e Interface) (done bool) { o = append(o, e); return }, q)
+	return
+}
+
+// Do performs fn on all intervals stored in the EndTree in end order. A boolean is returned
+// indicating whether the traversal was interrupted by an Operation returning true.
+func (t *EndTree) Do(fn Operation) bool { return t.t.DoReverse(unreflect(fn)) }
+
+// DoReverse performs fn on all intervals stored in the EndTree in reverse end order. A boolean
+// is returned indicating whether the traversal was interrupted by an Operation returning true.
+func (t *EndTree) DoReverse(fn Operation) bool { return t.t.Do(unreflect(fn)) }
+
+// DoMatching performs fn on all intervals stored in the EndTree that match q according to
+// q.Overlap(), in end order. A boolean is returned indicating whether the traversal was
+// interrupted by an Operation returning true.
+func (t *EndTree) DoMatching(fn Operation, q Overlapper) bool {
+	if q == nil {
+		return false
+	}
+	return t.t.DoMatchingReverse(unreflect(fn), reflectedQuery{q})
+}
@@ -0,0 +1,85 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"sort"
+)
+
+func (s *S) TestEndTree(c *check.C) {
+	var (
+		count, max = 500, 200
+		t          = NewByEnd()
+		all        []*overlap
+	)
+	c.Check(t.Min(), check.Equals, nil)
+	c.Check(t.Max(), check.Equals, nil)
+	c.Check(t.FloorEnd(compInt(0)), check.Equals, nil)
+	c.Check(t.CeilEnd(compInt(0)), check.Equals, nil)
+	for i := 0; i < count; i++ {
+		st := compInt(rand.Intn(max))
+		e := &overlap{start: st, end: st + 1 + compInt(rand.Intn(20)), id: uintptr(i)}
+		all = append(all, e)
+		c.Assert(t.Insert(e, false), check.Equals, nil)
+	}
+	c.Check(t.Len(), check.Equals, count)
+	c.Check(t.t.isBST(), check.Equals, true)
+
+	var ends []int
+	t.Do(func(e Interface) (done bool) { ends = append(ends, int(e.End().(compInt))); return })
+	c.Check(len(ends), check.Equals, count)
+	c.Check(sort.IntsAreSorted(ends), check.Equals, true)
+	c.Check(t.Min().End(), check.Equals, compInt(ends[0]))
+	c.Check(t.Max().End(), check.Equals, compInt(ends[len(ends)-1]))
+
+	for p := compInt(-1); p <= compInt(max+21); p++ {
+		var floor, ceil *overlap
+		for _, e := range all {
+			if e.end <= p && (floor == nil || e.end > floor.end) {
+				floor = e
+			}
+			if e.end >= p && (ceil == nil || e.end < ceil.end) {
+				ceil = e
+			}
+		}
+		if f := t.FloorEnd(p); floor == nil {
+			c.Check(f, check.Equals, nil)
+		} else {
+			c.Check(f.End(), check.Equals, floor.end)
+		}
+		if cl := t.CeilEnd(p); ceil == nil {
+			c.Check(cl, check.Equals, nil)
+		} else {
+			c.Check(cl.End(), check.Equals, ceil.end)
+		}
+	}
+
+	for st := compInt(0); st < compInt(max); st += 7 {
+		q := &overlap{start: st, end: st + 10}
+		got := make(map[Interface]bool)
+		var last compInt
+		for _, e := range t.Get(q) {
+			c.Check(e.End().(compInt) >= last, check.Equals, true)
+			last = e.End().(compInt)
+			got[e] = true
+		}
+		var n int
+		for _, e := range all {
+			if q.Overlap(e) {
+				n++
+				c.Check(got[e], check.Equals, true)
+			}
+		}
+		c.Check(len(got), check.Equals, n)
+	}
+
+	for i, e := range all {
+		c.Assert(t.Delete(e, false), check.Equals, nil)
+		c.Check(t.Len(), check.Equals, count-i-1)
+	}
+	c.Check(t.Insert(nil, false), check.Equals, ErrNilOverlapper)
+}
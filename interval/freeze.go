@@ -0,0 +1,36 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// Freeze makes the Tree read-only. After Freeze is called, methods of the Tree that alter it
+// and return an error return ErrFrozen without altering the Tree, or panic with ErrFrozen
+// when built with the interval_debug tag. Methods that alter the Tree without returning an
+// error, such as DeleteMin, AdjustRanges and Coalesce, panic with ErrFrozen. Query and
+// traversal methods are not affected. A Tree cannot be unfrozen, but a Clone of a frozen
+// Tree is not frozen. Freeze does not prevent the Tree's fields or the stored intervals from
+// being altered directly.
+func (t *Tree) Freeze() { t.frozen = true }
+
+// Frozen returns whether Freeze has been called on the Tree.
+func (t *Tree) Frozen() bool { return t.frozen }
+
+// writable returns ErrFrozen if the Tree is frozen. In debug builds it panics instead.
+func (t *Tree) writable() error {
+	if !t.frozen {
+		return nil
+	}
+	if debug {
+		panic(ErrFrozen)
+	}
+	return ErrFrozen
+}
+
+// mustBeWritable panics with ErrFrozen if the Tree is frozen. It is used by methods that
+// alter the Tree and have no error result.
+func (t *Tree) mustBeWritable() {
+	if t.frozen {
+		panic(ErrFrozen)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+)
+
+func (s *S) TestFreeze(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 10; i++ {
+		t.Insert(&overlap{start: i, end: i + 2, id: uintptr(i)}, false)
+	}
+	t.Freeze()
+	c.Check(t.Frozen(), check.Equals, true)
+	want := t.Slice()
+
+	e := &overlap{start: 20, end: 22, id: 20}
+	frozen := func(fn func() error) {
+		if debug {
+			c.Check(func() { fn() }, check.PanicMatches, ErrFrozen.Error())
+		} else {
+			c.Check(fn(), check.Equals, ErrFrozen)
+		}
+	}
+	frozen(func() error { return t.Insert(e, false) })
+	frozen(func() error { return t.InsertShared(e, func(a, b Interface) bool { return true }, false) })
+	frozen(func() error { return t.InsertBatch([]Interface{e}, false) })
+	frozen(func() error { return t.Delete(want[0], false) })
+	frozen(func() error { _, err := t.DeleteRange(compInt(0), compInt(5)); return err })
+	frozen(func() error { return t.Fix(want[0]) })
+	frozen(func() error { return t.Replace(want[0], want[0]) })
+	frozen(func() error { _, err := t.DeleteHandle(Handle{e: want[0]}, false); return err })
+	frozen(func() error { b, _ := t.GobEncode(); return t.GobDecode(b) })
+
+	for _, fn := range []func(){
+		func() { t.DeleteMin(false) },
+		func() { t.DeleteMax(false) },
+		func() { t.PopMin(false) },
+		func() { t.AdjustRanges() },
+		func() { t.Clear() },
+		func() { t.Rebalance() },
+		func() { t.Coalesce(func(a, b Interface) Interface { return a }) },
+		func() { t.DeleteAll(&overlap{start: 0, end: 100}, false) },
+		func() { t.Merge(&Tree{}, false) },
+	} {
+		c.Check(fn, check.PanicMatches, ErrFrozen.Error())
+	}
+
+	c.Check(t.Slice(), check.DeepEquals, want)
+	c.Check(t.Get(&overlap{start: 3, end: 4}), check.HasLen, 2)
+	c.Check(t.Min(), check.Equals, want[0])
+	c.Check(t.Validate(), check.Equals, nil)
+
+	ct := t.Clone()
+	c.Check(ct.Frozen(), check.Equals, false)
+	c.Check(ct.Insert(e, false), check.Equals, nil)
+}
@@ -0,0 +1,592 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.21
+
+// Package generic implements an interval tree based on an augmented Left-Leaning Red Black
+// tree where the end point and element types are type parameters. Storing elements as a
+// concrete type and comparing end points with the language's ordering operators avoids the
+// interface allocation and method dispatch costs incurred by the interval package.
+package generic
+
+import (
+	"cmp"
+	"code.google.com/p/biogo.store/interval"
+	"code.google.com/p/biogo.store/llrb"
+)
+
+// Mode is the operation mode of the underlying LLRB tree, shared with the interval package.
+const Mode = interval.Mode
+
+// ErrInvertedRange is returned if an interval is used where the start value is greater
+// than the end value.
+var ErrInvertedRange = interval.ErrInvertedRange
+
+// A Range describes the start and end values of an interval over an ordered type.
+type Range[P cmp.Ordered] struct {
+	Start, End P
+}
+
+// An Overlapper can determine whether it overlaps a range.
+type Overlapper[P cmp.Ordered] interface {
+	// Overlap returns a boolean indicating whether the receiver overlaps a range.
+	Overlap(Range[P]) bool
+}
+
+// An Interface is a type that can be inserted into a Tree.
+type Interface[P cmp.Ordered] interface {
+	Overlapper[P]
+	Range() Range[P]
+	ID() uintptr // Returns a unique ID for the element.
+}
+
+// A Node represents a node in a Tree.
+type Node[P cmp.Ordered, T Interface[P]] struct {
+	Elem        T
+	Interval    Range[P]
+	Range       Range[P]
+	Left, Right *Node[P, T]
+	Color       llrb.Color
+}
+
+// A Tree manages the root node of an interval tree holding elements of type T with end
+// points of type P. Public methods are exposed through this type.
+type Tree[P cmp.Ordered, T Interface[P]] struct {
+	Root  *Node[P, T] // Root node of the tree.
+	Count int         // Number of elements stored.
+}
+
+// Helper methods
+
+// color returns the effect color of a Node. A nil node returns black.
+func (n *Node[P, T]) color() llrb.Color {
+	if n == nil {
+		return llrb.Black
+	}
+	return n.Color
+}
+
+// maxRange returns the furthest right position held by the subtree
+// rooted at root, assuming that the left and right nodes have correct
+// range extents.
+func maxRange[P cmp.Ordered, T Interface[P]](root, left, right *Node[P, T]) P {
+	end := root.Interval.End
+	if left != nil && left.Range.End > end {
+		end = left.Range.End
+	}
+	if right != nil && right.Range.End > end {
+		end = right.Range.End
+	}
+	return end
+}
+
+// (a,c)b -rotL-> ((a,)b,)c
+func (n *Node[P, T]) rotateLeft() (root *Node[P, T]) {
+	// Assumes: n has a right child.
+	root = n.Right
+	n.Right = root.Left
+	root.Left = n
+	root.Color = n.Color
+	n.Color = llrb.Red
+
+	root.Left.Range.End = maxRange(root.Left, root.Left.Left, root.Left.Right)
+	root.Range.Start = root.Left.Range.Start
+	root.Range.End = maxRange(root, root.Left, root.Right)
+
+	return
+}
+
+// (a,c)b -rotR-> (,(,c)b)a
+func (n *Node[P, T]) rotateRight() (root *Node[P, T]) {
+	// Assumes: n has a left child.
+	root = n.Left
+	n.Left = root.Right
+	root.Right = n
+	root.Color = n.Color
+	n.Color = llrb.Red
+
+	if root.Right.Left == nil {
+		root.Right.Range.Start = root.Right.Interval.Start
+	} else {
+		root.Right.Range.Start = root.Right.Left.Range.Start
+	}
+	root.Right.Range.End = maxRange(root.Right, root.Right.Left, root.Right.Right)
+	root.Range.End = maxRange(root, root.Left, root.Right)
+
+	return
+}
+
+// (aR,cR)bB -flipC-> (aB,cB)bR | (aB,cB)bR -flipC-> (aR,cR)bB
+func (n *Node[P, T]) flipColors() {
+	// Assumes: n has two children.
+	n.Color = !n.Color
+	n.Left.Color = !n.Left.Color
+	n.Right.Color = !n.Right.Color
+}
+
+// fixUp ensures that black link balance is correct, that red nodes lean left,
+// and that 4 nodes are split in the case of BU23 and properly balanced in TD234.
+func (n *Node[P, T]) fixUp(fast bool) *Node[P, T] {
+	if !fast {
+		n.adjustRange()
+	}
+	if n.Right.color() == llrb.Red {
+		if Mode == interval.TD234 && n.Right.Left.color() == llrb.Red {
+			n.Right = n.Right.rotateRight()
+		}
+		n = n.rotateLeft()
+	}
+	if n.Left.color() == llrb.Red && n.Left.Left.color() == llrb.Red {
+		n = n.rotateRight()
+	}
+	if Mode == interval.BU23 && n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
+		n.flipColors()
+	}
+
+	return n
+}
+
+// adjustRange sets the Range to the maximum extent of the childrens' Range
+// spans and the node's Elem span.
+func (n *Node[P, T]) adjustRange() {
+	if n.Left == nil {
+		n.Range.Start = n.Interval.Start
+	} else {
+		n.Range.Start = n.Left.Range.Start
+	}
+	n.Range.End = maxRange(n, n.Left, n.Right)
+}
+
+func (n *Node[P, T]) moveRedLeft() *Node[P, T] {
+	n.flipColors()
+	if n.Right.Left.color() == llrb.Red {
+		n.Right = n.Right.rotateRight()
+		n = n.rotateLeft()
+		n.flipColors()
+		if Mode == interval.TD234 && n.Right.Right.color() == llrb.Red {
+			n.Right = n.Right.rotateLeft()
+		}
+	}
+	return n
+}
+
+func (n *Node[P, T]) moveRedRight() *Node[P, T] {
+	n.flipColors()
+	if n.Left.Left.color() == llrb.Red {
+		n = n.rotateRight()
+		n.flipColors()
+	}
+	return n
+}
+
+// before returns whether an interval starting at m with ID id sorts before the
+// interval held by n.
+func (n *Node[P, T]) before(m P, id uintptr) bool {
+	return m < n.Interval.Start || (m == n.Interval.Start && id < n.Elem.ID())
+}
+
+// Len returns the number of intervals stored in the Tree.
+func (t *Tree[P, T]) Len() int {
+	return t.Count
+}
+
+// Get returns a slice of elements that overlap q in the Tree according
+// to q.Overlap().
+func (t *Tree[P, T]) Get(q Overlapper[P]) (o []T) {
+	if t.Root != nil && q.Overlap(t.Root.Range) {
+		t.Root.doMatch(func(e T) (done bool) { o = append(o, e); return }, q)
+	}
+	return
+}
+
+// AdjustRanges fixes range fields for all Nodes in the Tree. This must be called
+// before Get or DoMatching* is used if fast insertion or deletion has been performed.
+func (t *Tree[P, T]) AdjustRanges() {
+	if t.Root == nil {
+		return
+	}
+	t.Root.adjustRanges()
+}
+
+func (n *Node[P, T]) adjustRanges() {
+	if n.Left != nil {
+		n.Left.adjustRanges()
+	}
+	if n.Right != nil {
+		n.Right.adjustRanges()
+	}
+	n.adjustRange()
+}
+
+// Insert inserts the element e into the Tree. Insertions may replace
+// existing stored intervals.
+func (t *Tree[P, T]) Insert(e T, fast bool) (err error) {
+	if r := e.Range(); r.Start > r.End {
+		return ErrInvertedRange
+	}
+	var d int
+	t.Root, d = t.Root.insert(e, e.Range(), e.ID(), fast)
+	t.Count += d
+	t.Root.Color = llrb.Black
+	return
+}
+
+func (n *Node[P, T]) insert(e T, r Range[P], id uintptr, fast bool) (root *Node[P, T], d int) {
+	if n == nil {
+		return &Node[P, T]{Elem: e, Interval: r, Range: r}, 1
+	}
+
+	if Mode == interval.TD234 {
+		if n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
+			n.flipColors()
+		}
+	}
+
+	switch {
+	case r.Start == n.Interval.Start && id == n.Elem.ID():
+		n.Elem = e
+		n.Interval = r
+		if !fast {
+			n.Range.End = r.End
+		}
+	case n.before(r.Start, id):
+		n.Left, d = n.Left.insert(e, r, id, fast)
+	default:
+		n.Right, d = n.Right.insert(e, r, id, fast)
+	}
+
+	if n.Right.color() == llrb.Red && n.Left.color() == llrb.Black {
+		n = n.rotateLeft()
+	}
+	if n.Left.color() == llrb.Red && n.Left.Left.color() == llrb.Red {
+		n = n.rotateRight()
+	}
+
+	if Mode == interval.BU23 {
+		if n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
+			n.flipColors()
+		}
+	}
+
+	if !fast {
+		n.adjustRange()
+	}
+	root = n
+
+	return
+}
+
+// DeleteMin deletes the left-most interval.
+func (t *Tree[P, T]) DeleteMin(fast bool) {
+	if t.Root == nil {
+		return
+	}
+	var d int
+	t.Root, d = t.Root.deleteMin(fast)
+	t.Count += d
+	if t.Root == nil {
+		return
+	}
+	t.Root.Color = llrb.Black
+}
+
+func (n *Node[P, T]) deleteMin(fast bool) (root *Node[P, T], d int) {
+	if n.Left == nil {
+		return nil, -1
+	}
+	if n.Left.color() == llrb.Black && n.Left.Left.color() == llrb.Black {
+		n = n.moveRedLeft()
+	}
+	n.Left, d = n.Left.deleteMin(fast)
+	if n.Left == nil {
+		n.Range.Start = n.Interval.Start
+	}
+
+	root = n.fixUp(fast)
+
+	return
+}
+
+// DeleteMax deletes the right-most interval.
+func (t *Tree[P, T]) DeleteMax(fast bool) {
+	if t.Root == nil {
+		return
+	}
+	var d int
+	t.Root, d = t.Root.deleteMax(fast)
+	t.Count += d
+	if t.Root == nil {
+		return
+	}
+	t.Root.Color = llrb.Black
+}
+
+func (n *Node[P, T]) deleteMax(fast bool) (root *Node[P, T], d int) {
+	if n.Left != nil && n.Left.color() == llrb.Red {
+		n = n.rotateRight()
+	}
+	if n.Right == nil {
+		return nil, -1
+	}
+	if n.Right.color() == llrb.Black && n.Right.Left.color() == llrb.Black {
+		n = n.moveRedRight()
+	}
+	n.Right, d = n.Right.deleteMax(fast)
+	if n.Right == nil {
+		n.Range.End = n.Interval.End
+	}
+
+	root = n.fixUp(fast)
+
+	return
+}
+
+// Delete deletes the element e if it exists in the Tree.
+func (t *Tree[P, T]) Delete(e T, fast bool) (err error) {
+	r := e.Range()
+	if r.Start > r.End {
+		return ErrInvertedRange
+	}
+	if t.Root == nil || r.Start < t.Root.Range.Start || r.Start > t.Root.Range.End {
+		return
+	}
+	var d int
+	t.Root, d = t.Root.delete(r.Start, e.ID(), fast)
+	t.Count += d
+	if t.Root == nil {
+		return
+	}
+	t.Root.Color = llrb.Black
+	return
+}
+
+func (n *Node[P, T]) delete(m P, id uintptr, fast bool) (root *Node[P, T], d int) {
+	if n.before(m, id) {
+		if n.Left != nil {
+			if n.Left.color() == llrb.Black && n.Left.Left.color() == llrb.Black {
+				n = n.moveRedLeft()
+			}
+			n.Left, d = n.Left.delete(m, id, fast)
+			if n.Left == nil {
+				n.Range.Start = n.Interval.Start
+			}
+		}
+	} else {
+		if n.Left.color() == llrb.Red {
+			n = n.rotateRight()
+		}
+		if n.Right == nil && m == n.Interval.Start && id == n.Elem.ID() {
+			return nil, -1
+		}
+		if n.Right != nil {
+			if n.Right.color() == llrb.Black && n.Right.Left.color() == llrb.Black {
+				n = n.moveRedRight()
+			}
+			if m == n.Interval.Start && id == n.Elem.ID() {
+				m := n.Right.min()
+				n.Elem = m.Elem
+				n.Interval = m.Interval
+				n.Right, d = n.Right.deleteMin(fast)
+			} else {
+				n.Right, d = n.Right.delete(m, id, fast)
+			}
+			if n.Right == nil {
+				n.Range.End = n.Interval.End
+			}
+		}
+	}
+
+	root = n.fixUp(fast)
+
+	return
+}
+
+// Min returns the left-most interval stored in the tree. If the tree is empty, the zero
+// value of T is returned.
+func (t *Tree[P, T]) Min() (e T) {
+	if t.Root == nil {
+		return
+	}
+	return t.Root.min().Elem
+}
+
+func (n *Node[P, T]) min() *Node[P, T] {
+	for ; n.Left != nil; n = n.Left {
+	}
+	return n
+}
+
+// Max returns the right-most interval stored in the tree. If the tree is empty, the zero
+// value of T is returned.
+func (t *Tree[P, T]) Max() (e T) {
+	if t.Root == nil {
+		return
+	}
+	return t.Root.max().Elem
+}
+
+func (n *Node[P, T]) max() *Node[P, T] {
+	for ; n.Right != nil; n = n.Right {
+	}
+	return n
+}
+
+// Floor returns the largest value equal to or less than the query q according to
+// q.Range().Start, with ties broken by comparison of ID() values. If no such value
+// is stored, the zero value of T is returned.
+func (t *Tree[P, T]) Floor(q T) (o T, err error) {
+	var f *Node[P, T]
+	m, id := q.Range().Start, q.ID()
+	for n := t.Root; n != nil; {
+		if n.before(m, id) {
+			n = n.Left
+		} else {
+			f, n = n, n.Right
+		}
+	}
+	if f == nil {
+		return
+	}
+	return f.Elem, nil
+}
+
+// Ceil returns the smallest value equal to or greater than the query q according to
+// q.Range().Start, with ties broken by comparison of ID() values. If no such value
+// is stored, the zero value of T is returned.
+func (t *Tree[P, T]) Ceil(q T) (o T, err error) {
+	var f *Node[P, T]
+	m, id := q.Range().Start, q.ID()
+	for n := t.Root; n != nil; {
+		if m < n.Interval.Start || (m == n.Interval.Start && id <= n.Elem.ID()) {
+			f, n = n, n.Left
+		} else {
+			n = n.Right
+		}
+	}
+	if f == nil {
+		return
+	}
+	return f.Elem, nil
+}
+
+// An Operation is a function that operates on an element. If done is returned true, the
+// Operation is indicating that no further work needs to be done and so the Do function should
+// traverse no further.
+type Operation[T any] func(T) (done bool)
+
+// Do performs fn on all intervals stored in the tree. A boolean is returned indicating whether the
+// Do traversal was interrupted by an Operation returning true. If fn alters stored intervals'
+// end points, future tree operation behaviors are undefined.
+func (t *Tree[P, T]) Do(fn Operation[T]) bool {
+	if t.Root == nil {
+		return false
+	}
+	return t.Root.do(fn)
+}
+
+func (n *Node[P, T]) do(fn Operation[T]) (done bool) {
+	if n.Left != nil {
+		done = n.Left.do(fn)
+		if done {
+			return
+		}
+	}
+	done = fn(n.Elem)
+	if done {
+		return
+	}
+	if n.Right != nil {
+		done = n.Right.do(fn)
+	}
+	return
+}
+
+// DoReverse performs fn on all intervals stored in the tree, but in reverse of sort order. A boolean
+// is returned indicating whether the Do traversal was interrupted by an Operation returning true.
+// If fn alters stored intervals' end points, future tree operation behaviors are undefined.
+func (t *Tree[P, T]) DoReverse(fn Operation[T]) bool {
+	if t.Root == nil {
+		return false
+	}
+	return t.Root.doReverse(fn)
+}
+
+func (n *Node[P, T]) doReverse(fn Operation[T]) (done bool) {
+	if n.Right != nil {
+		done = n.Right.doReverse(fn)
+		if done {
+			return
+		}
+	}
+	done = fn(n.Elem)
+	if done {
+		return
+	}
+	if n.Left != nil {
+		done = n.Left.doReverse(fn)
+	}
+	return
+}
+
+// DoMatching performs fn on all intervals stored in the tree that match q according to Overlap,
+// with q.Overlap() used to guide tree traversal, so DoMatching() will out perform Do() with a
+// called conditional function if the condition is based on sort order, but can not be reliably
+// used if the condition is independent of sort order. A boolean is returned indicating whether
+// the Do traversal was interrupted by an Operation returning true. If fn alters stored intervals'
+// end points, future tree operation behaviors are undefined.
+func (t *Tree[P, T]) DoMatching(fn Operation[T], q Overlapper[P]) bool {
+	if t.Root != nil && q.Overlap(t.Root.Range) {
+		return t.Root.doMatch(fn, q)
+	}
+	return false
+}
+
+func (n *Node[P, T]) doMatch(fn Operation[T], q Overlapper[P]) (done bool) {
+	if n.Left != nil && q.Overlap(n.Left.Range) {
+		done = n.Left.doMatch(fn, q)
+		if done {
+			return
+		}
+	}
+	if q.Overlap(n.Interval) {
+		done = fn(n.Elem)
+		if done {
+			return
+		}
+	}
+	if n.Right != nil && q.Overlap(n.Right.Range) {
+		done = n.Right.doMatch(fn, q)
+	}
+	return
+}
+
+// DoMatchingReverse performs fn on all intervals stored in the tree that match q according to
+// Overlap in reverse of sort order, with q.Overlap() used to guide tree traversal. A boolean is
+// returned indicating whether the Do traversal was interrupted by an Operation returning true.
+// If fn alters stored intervals' end points, future tree operation behaviors are undefined.
+func (t *Tree[P, T]) DoMatchingReverse(fn Operation[T], q Overlapper[P]) bool {
+	if t.Root != nil && q.Overlap(t.Root.Range) {
+		return t.Root.doMatchReverse(fn, q)
+	}
+	return false
+}
+
+func (n *Node[P, T]) doMatchReverse(fn Operation[T], q Overlapper[P]) (done bool) {
+	if n.Right != nil && q.Overlap(n.Right.Range) {
+		done = n.Right.doMatchReverse(fn, q)
+		if done {
+			return
+		}
+	}
+	if q.Overlap(n.Interval) {
+		done = fn(n.Elem)
+		if done {
+			return
+		}
+	}
+	if n.Left != nil && q.Overlap(n.Left.Range) {
+		done = n.Left.doMatchReverse(fn, q)
+	}
+	return
+}
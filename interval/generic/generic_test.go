@@ -0,0 +1,351 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.21
+
+package generic
+
+import (
+	"code.google.com/p/biogo.store/interval"
+	"code.google.com/p/biogo.store/llrb"
+	"fmt"
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"testing"
+)
+
+// Integrity checks - translated from http://www.cs.princeton.edu/~rs/talks/LLRB/Java/RedBlackBST.java
+
+// Is this tree a BST?
+func (t *Tree[P, T]) isBST() bool {
+	if t == nil || t.Root == nil {
+		return true
+	}
+	return t.Root.isBST(t.Min().Range().Start, t.Max().Range().Start)
+}
+
+// Are all the values in the BST rooted at x between min and max,
+// and does the same property hold for both subtrees?
+func (n *Node[P, T]) isBST(min, max P) bool {
+	if n == nil {
+		return true
+	}
+	if n.Interval.Start < min || n.Interval.Start > max {
+		return false
+	}
+	return n.Left.isBST(min, n.Interval.Start) && n.Right.isBST(n.Interval.Start, max)
+}
+
+// Test BU and TD234 invariants.
+func (t *Tree[P, T]) is23_234() bool {
+	if t == nil {
+		return true
+	}
+	return t.Root.is23_234()
+}
+func (n *Node[P, T]) is23_234() bool {
+	if n == nil {
+		return true
+	}
+	if Mode == interval.BU23 {
+		// If the node has two children, only one of them may be red.
+		// The other must be black...
+		if (n.Left != nil) && (n.Right != nil) {
+			if n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
+				return false
+			}
+		}
+		// and the red node should really should be the left one.
+		if n.Right.color() == llrb.Red {
+			return false
+		}
+	} else if Mode == interval.TD234 {
+		// This test is altered from that shown in the java since the trees
+		// shown in the paper do not conform to the test as it existed and the
+		// current situation does not break the 2-3-4 definition of the LLRB.
+		if n.Right.color() == llrb.Red && n.Left.color() == llrb.Black {
+			return false
+		}
+	} else {
+		panic("cannot reach")
+	}
+	if n.color() == llrb.Red && n.Left.color() == llrb.Red {
+		return false
+	}
+	return n.Left.is23_234() && n.Right.is23_234()
+}
+
+// Do all paths from root to leaf have same number of black edges?
+func (t *Tree[P, T]) isBalanced() bool {
+	if t == nil {
+		return true
+	}
+	var black int // number of black links on path from root to min
+	for x := t.Root; x != nil; x = x.Left {
+		if x.color() == llrb.Black {
+			black++
+		}
+	}
+	return t.Root.isBalanced(black)
+}
+
+// Does every path from the root to a leaf have the given number
+// of black links?
+func (n *Node[P, T]) isBalanced(black int) bool {
+	if n == nil && black == 0 {
+		return true
+	} else if n == nil && black != 0 {
+		return false
+	}
+	if n.color() == llrb.Black {
+		black--
+	}
+	return n.Left.isBalanced(black) && n.Right.isBalanced(black)
+}
+
+// Does every node correctly annotate the range of its children.
+func (t *Tree[P, T]) isRanged() bool {
+	if t == nil {
+		return true
+	}
+	return t.Root.isRanged()
+}
+func (n *Node[P, T]) isRanged() bool {
+	if n == nil {
+		return true
+	}
+	m := n.bounding(n.Interval)
+	return m == n.Range &&
+		n.Left.isRanged() &&
+		n.Right.isRanged()
+}
+func (n *Node[P, T]) bounding(m Range[P]) Range[P] {
+	m.Start = min(n.Interval.Start, m.Start)
+	m.End = max(n.Interval.End, m.End)
+	if n.Left != nil {
+		m = n.Left.bounding(m)
+	}
+	if n.Right != nil {
+		m = n.Right.bounding(m)
+	}
+	return m
+}
+
+// Test helpers
+
+type overlap struct {
+	start, end int
+	id         uintptr
+}
+
+func (o overlap) Overlap(b Range[int]) bool {
+	return o.end > b.Start && o.start < b.End
+}
+func (o overlap) ID() uintptr       { return o.id }
+func (o overlap) Range() Range[int] { return Range[int]{o.start, o.end} }
+func (o overlap) String() string    { return fmt.Sprintf("[%d,%d)#%d", o.start, o.end, o.id) }
+
+type fOverlap struct {
+	start, end float64
+	id         uintptr
+}
+
+func (o *fOverlap) Overlap(b Range[float64]) bool {
+	return o.end >= b.Start && o.start <= b.End
+}
+func (o *fOverlap) ID() uintptr           { return o.id }
+func (o *fOverlap) Range() Range[float64] { return Range[float64]{o.start, o.end} }
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestNilOperations(c *check.C) {
+	t := &Tree[int, overlap]{}
+	c.Check(t.Min(), check.Equals, overlap{})
+	c.Check(t.Max(), check.Equals, overlap{})
+	if Mode == interval.TD234 {
+		return
+	}
+	t.DeleteMin(false)
+	c.Check(*t, check.Equals, Tree[int, overlap]{})
+	t.DeleteMax(false)
+	c.Check(*t, check.Equals, Tree[int, overlap]{})
+}
+
+func (s *S) TestInsertion(c *check.C) {
+	var (
+		min, max = 0, 1000
+		t        = &Tree[int, overlap]{}
+		length   = 100
+	)
+	for i := min; i <= max; i++ {
+		t.Insert(overlap{start: i, end: i + length, id: uintptr(i)}, false)
+		c.Check(t.Len(), check.Equals, i+1)
+		c.Assert(t.isBST(), check.Equals, true)
+		c.Assert(t.is23_234(), check.Equals, true)
+		c.Assert(t.isBalanced(), check.Equals, true)
+		c.Assert(t.isRanged(), check.Equals, true)
+	}
+	c.Check(t.Min().start, check.Equals, min)
+	c.Check(t.Max().start, check.Equals, max)
+}
+
+func (s *S) TestFastInsertion(c *check.C) {
+	var (
+		min, max = 0, 1000
+		t        = &Tree[int, overlap]{}
+		length   = 100
+	)
+	for i := min; i <= max; i++ {
+		t.Insert(overlap{start: i, end: i + length, id: uintptr(i)}, true)
+		c.Assert(t.isBST(), check.Equals, true)
+		c.Assert(t.is23_234(), check.Equals, true)
+		c.Assert(t.isBalanced(), check.Equals, true)
+	}
+	t.AdjustRanges()
+	c.Check(t.isRanged(), check.Equals, true)
+}
+
+func (s *S) TestRandomDeletion(c *check.C) {
+	var (
+		count, max = 1000, 100
+		r          = make([]overlap, count)
+		t          = &Tree[int, overlap]{}
+		length     = 10
+	)
+	for i := range r {
+		s := rand.Intn(max)
+		r[i] = overlap{start: s, end: s + length, id: uintptr(rand.Intn(count))}
+		t.Insert(r[i], false)
+	}
+	for _, v := range r {
+		t.Delete(v, false)
+		c.Assert(t.isBST(), check.Equals, true)
+		c.Assert(t.is23_234(), check.Equals, true)
+		c.Assert(t.isBalanced(), check.Equals, true)
+		c.Assert(t.isRanged(), check.Equals, true)
+	}
+	c.Check(*t, check.Equals, Tree[int, overlap]{})
+}
+
+func (s *S) TestZeroWidthDeletion(c *check.C) {
+	t := &Tree[int, overlap]{}
+	t.Insert(overlap{start: 0, end: 5, id: 1}, false)
+	t.Insert(overlap{start: 5, end: 5, id: 2}, false)
+	c.Check(t.Delete(overlap{start: 5, end: 5, id: 2}, false), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 1)
+	c.Check(t.Min(), check.Equals, overlap{start: 0, end: 5, id: 1})
+	c.Check(t.isRanged(), check.Equals, true)
+}
+
+func (s *S) TestDeleteMinMax(c *check.C) {
+	var (
+		min, max = 0, 10
+		t        = &Tree[int, overlap]{}
+	)
+	for i := min; i <= max; i++ {
+		t.Insert(overlap{start: i, end: i + 1}, false)
+	}
+	for i, n := 0, max/2; i < n; i++ {
+		t.DeleteMin(false)
+		min++
+		c.Check(t.Min(), check.Equals, overlap{start: min, end: min + 1})
+		t.DeleteMax(false)
+		max--
+		c.Check(t.Max(), check.Equals, overlap{start: max, end: max + 1})
+		c.Assert(t.is23_234(), check.Equals, true)
+		c.Assert(t.isBalanced(), check.Equals, true)
+		c.Assert(t.isRanged(), check.Equals, true)
+	}
+	c.Check(t.Len(), check.Equals, 1)
+}
+
+func (s *S) TestGet(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree[int, overlap]{}
+		length     = 10
+		all        []overlap
+	)
+	for i := 0; i < count; i++ {
+		s := rand.Intn(max)
+		e := overlap{start: s, end: s + length, id: uintptr(i)}
+		t.Insert(e, false)
+		all = append(all, e)
+	}
+	for s := -length; s <= max+length; s++ {
+		q := overlap{start: s, end: s + 1}
+		var want []overlap
+		t.Do(func(e overlap) (done bool) {
+			if q.Overlap(e.Range()) {
+				want = append(want, e)
+			}
+			return
+		})
+		c.Check(t.Get(q), check.DeepEquals, want)
+
+		var rev []overlap
+		t.DoMatchingReverse(func(e overlap) (done bool) { rev = append(rev, e); return }, q)
+		c.Check(len(rev), check.Equals, len(want))
+		for i := range rev {
+			c.Check(rev[i], check.Equals, want[len(want)-1-i])
+		}
+	}
+	for _, e := range all {
+		f, _ := t.Floor(e)
+		c.Check(f, check.Equals, e)
+		u, _ := t.Ceil(e)
+		c.Check(u, check.Equals, e)
+	}
+}
+
+func (s *S) TestFloorCeil(c *check.C) {
+	t := &Tree[float64, *fOverlap]{}
+	for i := 0; i <= 100; i += 2 {
+		t.Insert(&fOverlap{start: float64(i), end: float64(i) + 0.5, id: uintptr(i)}, false)
+	}
+	for i := 1; i < 100; i += 2 {
+		f, _ := t.Floor(&fOverlap{start: float64(i), end: float64(i)})
+		c.Check(f.start, check.Equals, float64(i-1))
+		u, _ := t.Ceil(&fOverlap{start: float64(i), end: float64(i)})
+		c.Check(u.start, check.Equals, float64(i+1))
+	}
+	f, _ := t.Floor(&fOverlap{start: -1, end: -1})
+	c.Check(f, check.IsNil)
+	c.Check(t.Get(&fOverlap{start: 3, end: 4}), check.HasLen, 1)
+}
+
+// Benchmarks
+
+func BenchmarkInsert(b *testing.B) {
+	var (
+		t      = &Tree[int, overlap]{}
+		length = 10
+	)
+	for i := 0; i < b.N; i++ {
+		s := b.N - i
+		t.Insert(overlap{start: s, end: s + length, id: uintptr(s)}, false)
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	b.StopTimer()
+	var (
+		t      = &Tree[int, overlap]{}
+		length = 10
+	)
+	for i := 0; i < b.N; i++ {
+		s := b.N - i
+		t.Insert(overlap{start: s, end: s + length, id: uintptr(s)}, false)
+	}
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		s := b.N - i
+		t.Get(overlap{start: s, end: s + length})
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+)
+
+// GobEncode implements the gob.GobEncoder interface. The number of stored intervals is
+// written followed by the stored Interfaces in sort order. Since the stored intervals are
+// encoded as Interface values, their concrete types must be registered with gob.Register.
+func (t *Tree) GobEncode() ([]byte, error) {
+	elems := t.Slice()
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(len(elems))
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Encode(elems)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements the gob.GobDecoder interface. Any intervals held by the receiver
+// are discarded and the Tree is rebuilt from the decoded intervals, which GobEncode writes in
+// sort order, in O(n) time, keeping the receiver's node pool, hooks and node layout. The
+// concrete types of the encoded intervals must be registered with gob.Register.
+func (t *Tree) GobDecode(b []byte) error {
+	err := t.writable()
+	if err != nil {
+		return err
+	}
+	var (
+		n     int
+		elems []Interface
+	)
+	dec := gob.NewDecoder(bytes.NewReader(b))
+	err = dec.Decode(&n)
+	if err != nil {
+		return err
+	}
+	err = dec.Decode(&elems)
+	if err != nil {
+		return err
+	}
+	if len(elems) != n {
+		return errors.New("interval: gob length mismatch")
+	}
+	return t.load(elems)
+}
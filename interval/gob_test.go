@@ -0,0 +1,62 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"bytes"
+	"encoding/gob"
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+// exported is an interval type with exported fields for use with encoders.
+type exported struct {
+	S, E compInt
+	Id   uintptr
+}
+
+func (o *exported) Overlap(b Range) bool {
+	return o.E > b.Start().(compInt) && o.S < b.End().(compInt)
+}
+func (o *exported) ID() uintptr         { return o.Id }
+func (o *exported) Start() Comparable   { return o.S }
+func (o *exported) End() Comparable     { return o.E }
+func (o *exported) NewMutable() Mutable { return &overlap{o.S, o.E, o.Id} }
+
+func init() {
+	gob.Register(&exported{})
+}
+
+func (s *S) TestGob(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&exported{S: s, E: s + length, Id: uintptr(i)}, false)
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(t)
+	c.Assert(err, check.Equals, nil)
+	nt := NewCompact()
+	nt.Insert(&exported{S: -10, E: -5, Id: uintptr(count)}, false)
+	err = gob.NewDecoder(&buf).Decode(nt)
+	c.Assert(err, check.Equals, nil)
+	_, compact := nt.Root.Range.(*compactRange)
+	c.Check(compact, check.Equals, true)
+
+	c.Check(nt.Len(), check.Equals, t.Len())
+	c.Check(nt.isBST(), check.Equals, true)
+	c.Check(nt.is23_234(), check.Equals, true)
+	c.Check(nt.isBalanced(), check.Equals, true)
+	c.Check(nt.isRanged(), check.Equals, true)
+	for s := compInt(-length); s <= compInt(max)+length; s++ {
+		q := &exported{S: s, E: s + 1}
+		c.Check(nt.Get(q), check.DeepEquals, t.Get(q))
+	}
+}
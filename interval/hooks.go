@@ -0,0 +1,24 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// Hooks holds functions that are called when the structure of a Tree is altered during
+// rebalancing. Each function is passed the node at the root of the affected subtree before
+// the operation is performed. Nil functions are not called. Hooks must not alter the Tree.
+type Hooks struct {
+	OnRotateLeft  func(*Node) // Called before a left rotation.
+	OnRotateRight func(*Node) // Called before a right rotation.
+	OnFlip        func(*Node) // Called before the colors of a node and its children are flipped.
+}
+
+// SetHooks sets the hooks called when the Tree is rebalanced, replacing any previously set.
+// When no hooks are set, the cost to each rebalancing operation is a single nil check. Hooks
+// are not copied by Clone.
+func (t *Tree) SetHooks(h Hooks) {
+	if t.env == nil {
+		t.env = &treeEnv{}
+	}
+	t.env.hooks = h
+}
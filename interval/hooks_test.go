@@ -0,0 +1,46 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+)
+
+func (s *S) TestHooks(c *check.C) {
+	for _, t := range []*Tree{{}, NewWithPool()} {
+		var left, right, flip int
+		t.SetHooks(Hooks{
+			OnRotateLeft:  func(n *Node) { c.Check(n.Right, check.NotNil); left++ },
+			OnRotateRight: func(n *Node) { c.Check(n.Left, check.NotNil); right++ },
+			OnFlip:        func(*Node) { flip++ },
+		})
+		u := &Tree{}
+		for i := compInt(0); i < 1000; i++ {
+			e := &overlap{start: i, end: i + 10, id: uintptr(i)}
+			t.Insert(e, false)
+			u.Insert(e, false)
+		}
+		c.Check(left, check.Not(check.Equals), 0)
+		c.Check(right+flip, check.Not(check.Equals), 0)
+		c.Check(t.Validate(), check.Equals, nil)
+		c.Check(t.Slice(), check.DeepEquals, u.Slice())
+		c.Check(t.Height(), check.Equals, u.Height())
+
+		left, right, flip = 0, 0, 0
+		for i := compInt(0); i < 500; i++ {
+			t.Delete(&overlap{start: i, end: i + 10, id: uintptr(i)}, false)
+		}
+		c.Check(left+right+flip, check.Not(check.Equals), 0)
+		c.Check(t.Validate(), check.Equals, nil)
+
+		t.SetHooks(Hooks{})
+		left, right, flip = 0, 0, 0
+		for i := compInt(0); i < 500; i++ {
+			t.Insert(&overlap{start: i, end: i + 10, id: uintptr(i)}, false)
+		}
+		c.Check(left+right+flip, check.Equals, 0)
+		c.Check(t.Len(), check.Equals, 1000)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// An IDTree is an interval tree that assigns its own ID to each inserted interval. IDs are
+// assigned from a sequence that increases with each insertion, and are used in place of the
+// ID() values of the inserted intervals both to break ties between intervals with equal start
+// values and to delete intervals. Intervals with equal start values are therefore held in
+// insertion order, and intervals that are otherwise indistinguishable may be inserted and
+// deleted independently.
+//
+// An ID is held in the uintptr ID of the underlying Tree, so on 32-bit platforms IDs are only
+// unique for the first 1<<32 insertions.
+type IDTree struct {
+	t     Tree
+	next  uint64
+	elems map[uint64]sequenced
+}
+
+// NewIDTree returns a new empty IDTree.
+func NewIDTree() *IDTree { return &IDTree{elems: make(map[uint64]sequenced)} }
+
+// sequenced is an Interface identified by its sequence number in an IDTree.
+type sequenced struct {
+	Interface
+	seq uint64
+}
+
+func (s sequenced) ID() uintptr { return uintptr(s.seq) }
+
+// unsequence returns an Operation that calls fn with the original inserted intervals.
+func unsequence(fn Operation) Operation {
+	return func(e Interface) (done bool) { return fn(e.(sequenced).Interface) }
+}
+
+// Len returns the number of intervals stored in the IDTree.
+func (t *IDTree) Len() int { return t.t.Len() }
+
+// Insert inserts the Interface e into the IDTree and returns the ID assigned to it. The ID()
+// value of e is not used. The fast parameter has the same meaning as for Tree.Insert. If e is
+// nil, ErrNilOverlapper is returned and no ID is assigned.
+func (t *IDTree) Insert(e Interface, fast bool) (uint64, error) {
+	if e == nil {
+		return 0, ErrNilOverlapper
+	}
+	if t.elems == nil {
+		t.elems = make(map[uint64]sequenced)
+	}
+	s := sequenced{Interface: e, seq: t.next}
+	err := t.t.Insert(s, fast)
+	if err != nil {
+		return 0, err
+	}
+	t.elems[s.seq] = s
+	t.next++
+	return s.seq, nil
+}
+
+// DeleteID deletes the interval assigned id by Insert, returning whether it was found. No other
+// interval is altered. The fast parameter has the same meaning as for Tree.Delete.
+func (t *IDTree) DeleteID(id uint64, fast bool) bool {
+	s, ok := t.elems[id]
+	if !ok {
+		return false
+	}
+	t.t.Delete(s, fast)
+	delete(t.elems, id)
+	return true
+}
+
+// Elem returns the interval assigned id by Insert and true, or nil and false if no such
+// interval is stored in the IDTree.
+func (t *IDTree) Elem(id uint64) (Interface, bool) {
+	s, ok := t.elems[id]
+	if !ok {
+		return nil, false
+	}
+	return s.Interface, true
+}
+
+// AdjustRanges fixes range fields for all Nodes in the IDTree. This must be called before
+// Get or DoMatching is used if fast insertion or deletion has been performed.
+func (t *IDTree) AdjustRanges() { t.t.AdjustRanges() }
+
+// Get returns a slice of Interfaces that overlap q in the IDTree according to q.Overlap().
+func (t *IDTree) Get(q Overlapper) (o []Interface) {
+	t.DoMatching(func(e Interface) (done bool) { o = append(o, e); return }, q)
+	return
+}
+
+// GetIDs returns the IDs of the intervals that overlap q in the IDTree according to
+// q.Overlap(), in sort order.
+func (t *IDTree) GetIDs(q Overlapper) (ids []uint64) {
+	t.t.DoMatching(func(e Interface) (done bool) { ids = append(ids, e.(sequenced).seq); return }, q)
+	return
+}
+
+// Do performs fn on all intervals stored in the IDTree in sort order. A boolean is returned
+// indicating whether the traversal was interrupted by an Operation returning true.
+func (t *IDTree) Do(fn Operation) bool { return t.t.Do(unsequence(fn)) }
+
+// DoMatching performs fn on all intervals stored in the IDTree that match q according to
+// q.Overlap(), in sort order. A boolean is returned indicating whether the traversal was
+// interrupted by an Operation returning true.
+func (t *IDTree) DoMatching(fn Operation, q Overlapper) bool {
+	return t.t.DoMatching(unsequence(fn), q)
+}
@@ -0,0 +1,58 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+func (s *S) TestIDTree(c *check.C) {
+	var (
+		t   = NewIDTree()
+		ids []uint64
+	)
+	// All intervals are equal, including their own ID() values.
+	for i := 0; i < 100; i++ {
+		id, err := t.Insert(&overlap{start: 5, end: 10}, false)
+		c.Assert(err, check.Equals, nil)
+		ids = append(ids, id)
+	}
+	c.Check(t.Len(), check.Equals, 100)
+	c.Check(t.GetIDs(&overlap{start: 0, end: 6}), check.DeepEquals, ids)
+	c.Check(t.Get(&overlap{start: 20, end: 30}), check.HasLen, 0)
+	c.Check(t.t.Validate(), check.Equals, nil)
+
+	for _, i := range rand.Perm(len(ids))[:50] {
+		c.Check(t.DeleteID(ids[i], false), check.Equals, true)
+		c.Check(t.DeleteID(ids[i], false), check.Equals, false)
+		_, ok := t.Elem(ids[i])
+		c.Check(ok, check.Equals, false)
+	}
+	c.Check(t.Len(), check.Equals, 50)
+	c.Check(t.t.Validate(), check.Equals, nil)
+
+	var n int
+	t.Do(func(e Interface) (done bool) {
+		_, ok := e.(*overlap)
+		c.Check(ok, check.Equals, true)
+		n++
+		return
+	})
+	c.Check(n, check.Equals, 50)
+
+	e := &overlap{start: 1, end: 2}
+	id, err := t.Insert(e, false)
+	c.Check(err, check.Equals, nil)
+	c.Check(id, check.Equals, uint64(100))
+	got, ok := t.Elem(id)
+	c.Check(ok, check.Equals, true)
+	c.Check(got, check.Equals, Interface(e))
+
+	_, err = t.Insert(nil, false)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, err = (&IDTree{}).Insert(&overlap{start: 2, end: 1}, false)
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
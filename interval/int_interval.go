@@ -38,6 +38,9 @@ type IntNode struct {
 
 // A IntTree manages the root node of an integer line interval tree.
 // Public methods are exposed through this type.
+//
+// Intervals are held in ascending order of start value, with ties broken by ascending ID()
+// value, independent of the order of insertion.
 type IntTree struct {
 	Root  *IntNode // Root node of the tree.
 	Count int      // Number of elements stored.
@@ -219,13 +222,6 @@ func (t *IntTree) Insert(e IntInterface, fast bool) (err error) {
 func (n *IntNode) insert(e IntInterface, r IntRange, id uintptr, fast bool) (root *IntNode, d int) {
 	if n == nil {
 		return &IntNode{Elem: e, Interval: r, Range: r}, 1
-	} else if n.Elem == nil {
-		n.Elem = e
-		n.Interval = r
-		if !fast {
-			n.adjustRange()
-		}
-		return n, 1
 	}
 
 	if Mode == TD234 {
@@ -236,14 +232,14 @@ func (n *IntNode) insert(e IntInterface, r IntRange, id uintptr, fast bool) (roo
 
 	switch c := r.Start - n.Interval.Start; {
 	case c == 0:
-		switch cid := id - n.Elem.ID(); {
-		case cid == 0:
+		switch eid := n.Elem.ID(); {
+		case id == eid:
 			n.Elem = e
 			n.Interval = r
 			if !fast {
 				n.Range.End = r.End
 			}
-		case cid < 0:
+		case id < eid:
 			n.Left, d = n.Left.insert(e, r, id, fast)
 		default:
 			n.Right, d = n.Right.insert(e, r, id, fast)
@@ -373,14 +369,14 @@ func (n *IntNode) delete(m int, id uintptr, fast bool) (root *IntNode, d int) {
 		if n.Left.color() == llrb.Red {
 			n = n.rotateRight()
 		}
-		if n.Right == nil && id == n.Elem.ID() {
+		if n.Right == nil && m == n.Interval.Start && id == n.Elem.ID() {
 			return nil, -1
 		}
 		if n.Right != nil {
 			if n.Right.color() == llrb.Black && n.Right.Left.color() == llrb.Black {
 				n = n.moveRedRight()
 			}
-			if id == n.Elem.ID() {
+			if m == n.Interval.Start && id == n.Elem.ID() {
 				m := n.Right.min()
 				n.Elem = m.Elem
 				n.Interval = m.Interval
@@ -446,10 +442,10 @@ func (n *IntNode) floor(m int, id uintptr) *IntNode {
 	}
 	switch c := m - n.Interval.Start; {
 	case c == 0:
-		switch cid := id - n.Elem.ID(); {
-		case cid == 0:
+		switch eid := n.Elem.ID(); {
+		case id == eid:
 			return n
-		case cid < 0:
+		case id < eid:
 			return n.Left.floor(m, id)
 		default:
 			if r := n.Right.floor(m, id); r != nil {
@@ -485,10 +481,10 @@ func (n *IntNode) ceil(m int, id uintptr) *IntNode {
 	}
 	switch c := m - n.Interval.Start; {
 	case c == 0:
-		switch cid := id - n.Elem.ID(); {
-		case cid == 0:
+		switch eid := n.Elem.ID(); {
+		case id == eid:
 			return n
-		case cid > 0:
+		case id > eid:
 			return n.Right.ceil(m, id)
 		default:
 			if l := n.Left.ceil(m, id); l != nil {
@@ -846,3 +846,48 @@ func BenchmarkIntFastDeleteMin(b *testing.B) {
 		t.DeleteMin(true)
 	}
 }
+
+func (s *S) TestIntDeterministicOrder(c *check.C) {
+	var elems []*intOverlap
+	for i, id := range rand.Perm(200) {
+		elems = append(elems, &intOverlap{start: i % 10, end: i%10 + 1 + rand.Intn(10), id: uintptr(id)})
+	}
+	var want []IntInterface
+	for trial := 0; trial < 5; trial++ {
+		t := &IntTree{}
+		for _, i := range rand.Perm(len(elems)) {
+			t.Insert(elems[i], false)
+		}
+		var got []IntInterface
+		t.Do(func(e IntInterface) (done bool) { got = append(got, e); return })
+		for i := 1; i < len(got); i++ {
+			a, b := got[i-1].Range().Start, got[i].Range().Start
+			c.Check(a < b || (a == b && got[i-1].ID() < got[i].ID()), check.Equals, true)
+		}
+		if want == nil {
+			want = got
+		} else {
+			c.Check(got, check.DeepEquals, want)
+		}
+		for _, i := range rand.Perm(len(elems)) {
+			c.Check(t.Delete(elems[i], false), check.Equals, nil)
+		}
+		c.Check(t.Len(), check.Equals, 0)
+	}
+}
+
+func (s *S) TestIntDeleteAbsentSharedID(c *check.C) {
+	t := &IntTree{}
+	for i := 0; i < 20; i++ {
+		t.Insert(&intOverlap{start: i * 10, end: i*10 + 5, id: 1}, false)
+	}
+	for i := 0; i < 20; i++ {
+		c.Check(t.Delete(&intOverlap{start: i*10 + 1, end: i*10 + 2, id: 1}, false), check.Equals, nil)
+		c.Check(t.Len(), check.Equals, 20)
+		c.Check(t.isBST(), check.Equals, true)
+	}
+	for i := 0; i < 20; i++ {
+		c.Check(t.Delete(&intOverlap{start: i * 10, end: i*10 + 5, id: 1}, false), check.Equals, nil)
+		c.Check(t.Len(), check.Equals, 19-i)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"fmt"
+)
+
+// An IntComparable is an int satisfying the Comparable interface.
+type IntComparable int
+
+// Compare returns -1, 0 or 1 as the receiver is less than, equal to or greater than b, which
+// must be an IntComparable. The values are compared directly, so extreme values do not
+// overflow as a difference would.
+func (c IntComparable) Compare(b Comparable) int {
+	switch d := b.(IntComparable); {
+	case c < d:
+		return -1
+	case c > d:
+		return 1
+	}
+	return 0
+}
+
+// An IntSpan is a ready-made half-open interval, [Low, High), over the integer number line
+// that may be stored in a Tree. Its end values are IntComparable. IntSpan is used through
+// a pointer, which satisfies both Interface and Mutable.
+type IntSpan struct {
+	Low, High int
+	UID       uintptr
+}
+
+// Overlap returns whether the half-open interval [Low, High) overlaps the half-open range b.
+// The end values of b must be IntComparable.
+func (s *IntSpan) Overlap(b Range) bool {
+	return IntComparable(s.Low).Compare(b.End()) < 0 && b.Start().Compare(IntComparable(s.High)) < 0
+}
+
+// Start returns the start value of the interval.
+func (s *IntSpan) Start() Comparable { return IntComparable(s.Low) }
+
+// End returns the end value of the interval.
+func (s *IntSpan) End() Comparable { return IntComparable(s.High) }
+
+// SetStart sets the start value of the interval. c must be an IntComparable.
+func (s *IntSpan) SetStart(c Comparable) { s.Low = int(c.(IntComparable)) }
+
+// SetEnd sets the end value of the interval. c must be an IntComparable.
+func (s *IntSpan) SetEnd(c Comparable) { s.High = int(c.(IntComparable)) }
+
+// ID returns the UID of the interval.
+func (s *IntSpan) ID() uintptr { return s.UID }
+
+// NewMutable returns a new IntSpan with the same end values as the receiver.
+func (s *IntSpan) NewMutable() Mutable { return &IntSpan{Low: s.Low, High: s.High} }
+
+// String returns a string representation of the interval.
+func (s *IntSpan) String() string { return fmt.Sprintf("[%d,%d)#%d", s.Low, s.High, s.UID) }
@@ -0,0 +1,86 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+var (
+	_ Interface = (*IntSpan)(nil)
+	_ Mutable   = (*IntSpan)(nil)
+)
+
+func (s *S) TestIntSpanOverlap(c *check.C) {
+	for _, test := range []struct {
+		a, b IntSpan
+		want bool
+	}{
+		{IntSpan{Low: 0, High: 10}, IntSpan{Low: 5, High: 15}, true},
+		{IntSpan{Low: 0, High: 10}, IntSpan{Low: 10, High: 15}, false},
+		{IntSpan{Low: 10, High: 15}, IntSpan{Low: 0, High: 10}, false},
+		{IntSpan{Low: 0, High: 10}, IntSpan{Low: 2, High: 3}, true},
+		{IntSpan{Low: 2, High: 3}, IntSpan{Low: 0, High: 10}, true},
+		{IntSpan{Low: 0, High: 10}, IntSpan{Low: 11, High: 15}, false},
+	} {
+		a, b := test.a, test.b
+		c.Check(a.Overlap(&b), check.Equals, test.want, check.Commentf("%v %v", &a, &b))
+	}
+}
+
+func (s *S) TestIntComparable(c *check.C) {
+	const (
+		maxInt = IntComparable(^uint(0) >> 1)
+		minInt = -maxInt - 1
+	)
+	for _, test := range []struct {
+		a, b IntComparable
+		want int
+	}{
+		{1, 2, -1},
+		{2, 2, 0},
+		{3, 2, 1},
+		{minInt, maxInt, -1},
+		{maxInt, minInt, 1},
+		{maxInt, -1, 1},
+	} {
+		c.Check(test.a.Compare(test.b), check.Equals, test.want, check.Commentf("%d %d", test.a, test.b))
+	}
+}
+
+func (s *S) TestIntSpanTree(c *check.C) {
+	t := &Tree{}
+	var spans []*IntSpan
+	for i := 0; i < 1000; i++ {
+		st := rand.Intn(1000)
+		e := &IntSpan{Low: st, High: st + 1 + rand.Intn(20), UID: uintptr(i)}
+		spans = append(spans, e)
+		c.Assert(t.Insert(e, false), check.Equals, nil)
+	}
+	c.Check(t.Validate(), check.Equals, nil)
+	q := &IntSpan{Low: 400, High: 450}
+	var n int
+	for _, e := range spans {
+		if e.Low < q.High && q.Low < e.High {
+			n++
+		}
+	}
+	got := t.Get(q)
+	c.Check(len(got), check.Equals, n)
+	for _, e := range got {
+		c.Check(q.Overlap(e), check.Equals, true)
+	}
+	for _, e := range spans {
+		c.Assert(t.Delete(e, false), check.Equals, nil)
+	}
+	c.Check(t.Len(), check.Equals, 0)
+
+	m := q.NewMutable()
+	m.SetStart(IntComparable(1))
+	m.SetEnd(IntComparable(2))
+	c.Check(m, check.DeepEquals, &IntSpan{Low: 1, High: 2})
+	c.Check(q.String(), check.Equals, "[400,450)#0")
+}
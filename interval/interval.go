@@ -8,7 +8,11 @@ package interval
 
 import (
 	"code.google.com/p/biogo.store/llrb"
+	"container/heap"
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 )
 
 // Operation mode of the underlying LLRB tree.
@@ -27,7 +31,53 @@ func init() {
 // than the end value.
 var ErrInvertedRange = errors.New("interval: inverted range")
 
+// ErrUnsorted is returned if a slice of intervals that is required to be in sort order
+// is not sorted.
+var ErrUnsorted = errors.New("interval: intervals not in sort order")
+
+// ErrOutOfRange is returned if an index into the sort order of a Tree is out of range.
+var ErrOutOfRange = errors.New("interval: index out of range")
+
+// ErrNotFound is returned if an interval required by an operation is not stored in the Tree.
+var ErrNotFound = errors.New("interval: interval not found")
+
+// ErrMismatchedKey is returned if an interval is used to replace a stored interval that
+// does not have the same start, end and ID values.
+var ErrMismatchedKey = errors.New("interval: mismatched key")
+
+// ErrDuplicate is returned by InsertShared if an interval with the same start and ID values
+// as the interval being inserted is already stored in the Tree, and by Map if more than one
+// result has the same start and ID values.
+var ErrDuplicate = errors.New("interval: duplicate interval")
+
+// ErrInvalidChunkSize is returned by DoChunked if the requested batch size is less than one.
+var ErrInvalidChunkSize = errors.New("interval: invalid chunk size")
+
+// ErrInvalidMode is returned if an OverlapMode is not one of the defined modes.
+var ErrInvalidMode = errors.New("interval: invalid overlap mode")
+
+// ErrNilOverlapper is returned if a nil Interface is passed to a method that requires one.
+var ErrNilOverlapper = errors.New("interval: nil overlapper")
+
+// ErrInvalidTree is wrapped by errors returned by Validate that describe a broken tree
+// invariant, so they can be detected with errors.Is.
+var ErrInvalidTree = errors.New("interval: invalid tree")
+
+// ErrAliasedMutable is returned by CheckMutable if altering the Mutable returned by an
+// Interface's NewMutable method alters the Interface.
+var ErrAliasedMutable = errors.New("interval: mutable aliases interval")
+
+// ErrFrozen is returned when an operation would alter a frozen Tree.
+var ErrFrozen = errors.New("interval: tree is frozen")
+
 // An Overlapper can determine whether it overlaps a range.
+//
+// Queries are pruned using the ranges of subtrees, so Overlap must be monotonic: if the
+// receiver overlaps a range, it must also overlap every range containing it. Under this
+// contract zero-width intervals, those with equal start and end values, are treated like
+// any other interval; a stored zero-width interval at x is matched by exactly the queries
+// whose Overlap method reports an overlap with the range [x, x], independent of the shape
+// of the Tree.
 type Overlapper interface {
 	// Overlap returns a boolean indicating whether the receiver overlaps the parameter.
 	Overlap(Range) bool
@@ -41,7 +91,9 @@ type Range interface {
 	End() Comparable
 }
 
-// An Interface is a type that can be inserted into a Tree.
+// An Interface is a type that can be inserted into a Tree. The Mutable returned by NewMutable
+// is used to hold node ranges and is altered as the Tree is rebalanced, so it must not share
+// storage with the Interface; CheckMutable may be used to test this.
 type Interface interface {
 	Overlapper
 	Range
@@ -56,6 +108,43 @@ type Mutable interface {
 	SetEnd(Comparable)   // Set the end value.
 }
 
+// CheckMutable checks that the Mutable returned by e.NewMutable() does not share storage with
+// e, returning ErrAliasedMutable if setting its start or end value alters e. Node ranges are
+// altered during rebalancing, so an aliased Mutable silently corrupts stored intervals. The
+// check can only detect aliasing when e has distinct start and end values; e is restored
+// before CheckMutable returns. Insert performs the check when built with the interval_debug
+// tag.
+func CheckMutable(e Interface) error {
+	start, end := e.Start(), e.End()
+	if start.Compare(end) == 0 {
+		return nil
+	}
+	var aliased bool
+	m := e.NewMutable()
+	m.SetStart(end)
+	if e.Start().Compare(start) != 0 {
+		aliased = true
+		m.SetStart(start)
+	}
+	m.SetEnd(start)
+	if e.End().Compare(end) != 0 {
+		aliased = true
+		m.SetEnd(end)
+	}
+	if aliased {
+		return ErrAliasedMutable
+	}
+	return nil
+}
+
+// A Weighted is an Interface with an associated weight. A Tree created by NewWeighted
+// maintains the sum of the weights of the Weighted intervals stored in each subtree.
+// Intervals that do not implement Weighted have a weight of zero.
+type Weighted interface {
+	Interface
+	Weight() float64 // Returns the weight of the interval.
+}
+
 // A Comparable is a type that describes the ends of an Overlapper.
 type Comparable interface {
 	// Compare returns a value indicating the sort order relationship between the
@@ -75,12 +164,31 @@ type Node struct {
 	Range       Mutable
 	Left, Right *Node
 	Color       llrb.Color
+	weighted    bool    // Whether Weight is maintained for the Node.
+	Size        int     // Number of intervals stored in the subtree rooted at the Node.
+	Weight      float64 // Sum of the weights of the intervals stored in the subtree, see NewWeighted.
+
+	// Shared holds intervals added with InsertShared that have the start and end
+	// values of Elem. They are held in sort order and no other stored interval sorts
+	// between Elem and the last of them, so they are visited immediately after Elem.
+	Shared []Interface
 }
 
 // A Tree manages the root node of an interval tree. Public methods are exposed through this type.
+//
+// Intervals are held in sort order: ascending start value according to Compare, with ties
+// broken by ascending ID() value. The sort order, and so the order of traversal by Do and
+// the other ordered methods, does not depend on the order of insertion.
+//
+// The number of stored intervals is held in Count, an int, and is updated exactly by each
+// operation. Every stored interval requires its own Node or Shared slot, so the address space
+// is exhausted long before Count could overflow on either 32 or 64-bit platforms.
 type Tree struct {
 	Root  *Node // Root node of the tree.
-	Count int   // Number of elements stored.
+	Count int   // Number of elements stored. See the Tree documentation for its limit.
+
+	env    *treeEnv // Node pool and rebalancing hooks; nil if neither is used.
+	frozen bool     // Whether the Tree has been frozen by Freeze.
 }
 
 // Helper methods
@@ -93,6 +201,101 @@ func (n *Node) color() llrb.Color {
 	return n.Color
 }
 
+// size returns the number of intervals stored in the subtree rooted at n. A nil node
+// returns zero.
+func (n *Node) size() int {
+	if n == nil {
+		return 0
+	}
+	return n.Size
+}
+
+// adjustSize sets the Size to the sum of the childrens' Size values and the node's Elem,
+// and for a weighted node, its Weight to the sum of the childrens' weights and the weights
+// of its own intervals.
+func (n *Node) adjustSize() {
+	n.Size = n.Left.size() + n.Right.size() + 1 + len(n.Shared)
+	if n.weighted {
+		n.Weight = n.Left.weight() + n.Right.weight() + n.ownWeight()
+	}
+}
+
+// ownWeight returns the sum of the weights of the Elem and Shared intervals held by n.
+func (n *Node) ownWeight() float64 {
+	w := weightOf(n.Elem)
+	for _, e := range n.Shared {
+		w += weightOf(e)
+	}
+	return w
+}
+
+// each performs fn on the Elem and then the Shared intervals held by n, returning
+// whether fn returned true.
+func (n *Node) each(fn Operation) (done bool) {
+	if fn(n.Elem) {
+		return true
+	}
+	for _, e := range n.Shared {
+		if fn(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// eachReverse performs fn on the Shared intervals held by n in reverse order and then
+// on the Elem, returning whether fn returned true.
+func (n *Node) eachReverse(fn Operation) (done bool) {
+	for i := len(n.Shared) - 1; i >= 0; i-- {
+		if fn(n.Shared[i]) {
+			return true
+		}
+	}
+	return fn(n.Elem)
+}
+
+// promote replaces the Elem of n with the first of its Shared intervals, which follows
+// the Elem in sort order.
+func (n *Node) promote() {
+	n.Elem, n.Shared = n.Shared[0], n.Shared[1:]
+	n.adjustSize()
+}
+
+// sharedIndex returns the index of the first Shared interval of n that does not sort
+// before the key (m, id), or len(n.Shared) if there is none.
+func (n *Node) sharedIndex(m Comparable, id uintptr) int {
+	return sort.Search(len(n.Shared), func(i int) bool { return compare(m, id, n.Shared[i]) <= 0 })
+}
+
+// deleteShared deletes the Shared interval of n with the key (m, id), returning whether
+// it was found.
+func (n *Node) deleteShared(m Comparable, id uintptr) bool {
+	i := n.sharedIndex(m, id)
+	if i == len(n.Shared) || compare(m, id, n.Shared[i]) != 0 {
+		return false
+	}
+	n.Shared = append(n.Shared[:i:i], n.Shared[i+1:]...)
+	n.adjustSize()
+	return true
+}
+
+// weight returns the Weight of the node. A nil node or a node that is not weighted
+// returns zero.
+func (n *Node) weight() float64 {
+	if n == nil || !n.weighted {
+		return 0
+	}
+	return n.Weight
+}
+
+// weightOf returns the weight of e if it is Weighted, and zero otherwise.
+func weightOf(e Interface) float64 {
+	if w, ok := e.(Weighted); ok {
+		return w.Weight()
+	}
+	return 0
+}
+
 // maxRange returns the furthest right position held by the subtree
 // rooted at root, assuming that the left and right nodes have correct
 // range extents.
@@ -107,14 +310,35 @@ func maxRange(root, left, right *Node) Comparable {
 	return end
 }
 
+// compare returns a value indicating the sort order relationship between an interval with
+// start value m and ID id, and the interval e. Ties in start value are broken by comparison
+// of ID values.
+func compare(m Comparable, id uintptr, e Interface) int {
+	switch c := m.Compare(e.Start()); {
+	case c != 0:
+		return c
+	case id < e.ID():
+		return -1
+	case id > e.ID():
+		return 1
+	}
+	return 0
+}
+
 // (a,c)b -rotL-> ((a,)b,)c
-func (n *Node) rotateLeft() (root *Node) {
+func (n *Node) rotateLeft(p *treeEnv) (root *Node) {
 	// Assumes: n has a right child.
+	if p != nil && p.hooks.OnRotateLeft != nil {
+		p.hooks.OnRotateLeft(n)
+	}
 	root = n.Right
 	n.Right = root.Left
 	root.Left = n
 	root.Color = n.Color
 	n.Color = llrb.Red
+	root.Size = n.Size
+	root.Weight = n.Weight
+	n.adjustSize()
 
 	root.Left.Range.SetEnd(maxRange(root.Left, root.Left.Left, root.Left.Right))
 	if root.Left == nil {
@@ -128,13 +352,19 @@ func (n *Node) rotateLeft() (root *Node) {
 }
 
 // (a,c)b -rotR-> (,(,c)b)a
-func (n *Node) rotateRight() (root *Node) {
+func (n *Node) rotateRight(p *treeEnv) (root *Node) {
 	// Assumes: n has a left child.
+	if p != nil && p.hooks.OnRotateRight != nil {
+		p.hooks.OnRotateRight(n)
+	}
 	root = n.Left
 	n.Left = root.Right
 	root.Right = n
 	root.Color = n.Color
 	n.Color = llrb.Red
+	root.Size = n.Size
+	root.Weight = n.Weight
+	n.adjustSize()
 
 	if root.Right.Left == nil {
 		root.Right.Range.SetStart(root.Right.Elem.Start())
@@ -148,8 +378,11 @@ func (n *Node) rotateRight() (root *Node) {
 }
 
 // (aR,cR)bB -flipC-> (aB,cB)bR | (aB,cB)bR -flipC-> (aR,cR)bB
-func (n *Node) flipColors() {
+func (n *Node) flipColors(p *treeEnv) {
 	// Assumes: n has two children.
+	if p != nil && p.hooks.OnFlip != nil {
+		p.hooks.OnFlip(n)
+	}
 	n.Color = !n.Color
 	n.Left.Color = !n.Left.Color
 	n.Right.Color = !n.Right.Color
@@ -157,21 +390,22 @@ func (n *Node) flipColors() {
 
 // fixUp ensures that black link balance is correct, that red nodes lean left,
 // and that 4 nodes are split in the case of BU23 and properly balanced in TD234.
-func (n *Node) fixUp(fast bool) *Node {
+func (n *Node) fixUp(fast bool, p *treeEnv) *Node {
+	n.adjustSize()
 	if !fast {
 		n.adjustRange()
 	}
 	if n.Right.color() == llrb.Red {
 		if Mode == TD234 && n.Right.Left.color() == llrb.Red {
-			n.Right = n.Right.rotateRight()
+			n.Right = n.Right.rotateRight(p)
 		}
-		n = n.rotateLeft()
+		n = n.rotateLeft(p)
 	}
 	if n.Left.color() == llrb.Red && n.Left.Left.color() == llrb.Red {
-		n = n.rotateRight()
+		n = n.rotateRight(p)
 	}
 	if Mode == BU23 && n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
-		n.flipColors()
+		n.flipColors(p)
 	}
 
 	return n
@@ -188,25 +422,133 @@ func (n *Node) adjustRange() {
 	n.Range.SetEnd(maxRange(n, n.Left, n.Right))
 }
 
-func (n *Node) moveRedLeft() *Node {
-	n.flipColors()
+func (n *Node) moveRedLeft(p *treeEnv) *Node {
+	n.flipColors(p)
 	if n.Right.Left.color() == llrb.Red {
-		n.Right = n.Right.rotateRight()
-		n = n.rotateLeft()
-		n.flipColors()
+		n.Right = n.Right.rotateRight(p)
+		n = n.rotateLeft(p)
+		n.flipColors(p)
 		if Mode == TD234 && n.Right.Right.color() == llrb.Red {
-			n.Right = n.Right.rotateLeft()
+			n.Right = n.Right.rotateLeft(p)
 		}
 	}
 	return n
 }
 
-func (n *Node) moveRedRight() *Node {
-	n.flipColors()
+func (n *Node) moveRedRight(p *treeEnv) *Node {
+	n.flipColors(p)
 	if n.Left.Left.color() == llrb.Red {
-		n = n.rotateRight()
-		n.flipColors()
+		n = n.rotateRight(p)
+		n.flipColors(p)
+	}
+	return n
+}
+
+// NewFromSorted returns a Tree holding the intervals in elems, which must be in strictly
+// increasing sort order according to Start().Compare(), with ties broken by comparison of
+// ID() values. The Tree is built in a single O(n) pass without rebalancing. If elems is not
+// sorted, ErrUnsorted is returned and if any interval has a start value greater than its end
+// value, ErrInvertedRange is returned.
+func NewFromSorted(elems []Interface) (*Tree, error) {
+	for i, e := range elems {
+		if e.Start().Compare(e.End()) > 0 {
+			return nil, ErrInvertedRange
+		}
+		if i > 0 && compare(e.Start(), e.ID(), elems[i-1]) <= 0 {
+			return nil, ErrUnsorted
+		}
+	}
+	t := &Tree{}
+	t.rebuild(elems)
+	return t, nil
+}
+
+// rebuild replaces the contents of the Tree with a balanced tree holding elems. If elems is
+// not in sort order, as may be the case when it holds shared intervals, it is sorted. The
+// nodes of the replaced tree are returned to the Tree's pool.
+func (t *Tree) rebuild(elems []Interface) {
+	if !sort.IsSorted(byKey(elems)) {
+		sort.Sort(byKey(elems))
+	}
+	t.env.putAll(t.Root)
+	var black int
+	for 1<<uint(black+1)-1 <= len(elems) {
+		black++
+	}
+	t.Root, t.Count = build(elems, black, t.env), len(elems)
+}
+
+// load replaces the contents of the Tree with a balanced tree holding elems, which may be in
+// any order. Of intervals with the same start and ID values, only the last in elems is kept,
+// as if elems had been inserted in turn. If an interval is nil or inverted, ErrNilOverlapper
+// or ErrInvertedRange is returned and the Tree is not altered. The Tree keeps its node pool,
+// hooks and node layout.
+func (t *Tree) load(elems []Interface) error {
+	for _, e := range elems {
+		if e == nil {
+			return ErrNilOverlapper
+		}
+		if e.Start().Compare(e.End()) > 0 {
+			return ErrInvertedRange
+		}
+	}
+	if !sort.IsSorted(byKey(elems)) {
+		sort.Stable(byKey(elems))
+	}
+	w := 0
+	for _, e := range elems {
+		if w > 0 && compare(e.Start(), e.ID(), elems[w-1]) == 0 {
+			elems[w-1] = e
+			continue
+		}
+		elems[w] = e
+		w++
+	}
+	t.rebuild(elems[:w])
+	return nil
+}
+
+// build returns the black root of a subtree holding elems where every path from the root
+// to a leaf has black black nodes. The subtree is constructed as a 2-3 tree, so len(elems)
+// must be in [2^black-1, 3^black-1].
+func build(elems []Interface, black int, p *treeEnv) *Node {
+	if len(elems) == 0 {
+		return nil
+	}
+
+	// Find the maximum number of elements that can be held by a
+	// child subtree, saturating at the number of elements held.
+	max := 1
+	for i := 0; i < black-1 && max <= len(elems); i++ {
+		max *= 3
 	}
+	max--
+
+	var n *Node
+	if len(elems)-1 <= 2*max {
+		// Make a 2-node.
+		m := (len(elems) - 1) / 2
+		n = p.get(elems[m])
+		n.Left = build(elems[:m], black-1, p)
+		n.Right = build(elems[m+1:], black-1, p)
+	} else {
+		// Make a 3-node.
+		k := len(elems) - 2
+		a := k / 3
+		b := a + 1 + (k-a)/2
+		l := p.get(elems[a])
+		l.Left = build(elems[:a], black-1, p)
+		l.Right = build(elems[a+1:b], black-1, p)
+		l.adjustSize()
+		l.adjustRange()
+		n = p.get(elems[b])
+		n.Left = l
+		n.Right = build(elems[b+1:], black-1, p)
+	}
+	n.Color = llrb.Black
+	n.adjustSize()
+	n.adjustRange()
+
 	return n
 }
 
@@ -215,214 +557,1903 @@ func (t *Tree) Len() int {
 	return t.Count
 }
 
-// Get returns a slice of Interfaces that overlap q in the Tree according
-// to q.Overlap().
-func (t *Tree) Get(q Overlapper) (o []Interface) {
-	if t.Root != nil && q.Overlap(t.Root.Range) {
-		t.Root.doMatch(func(e Interface) (done bool) { o = append(o, e); return }, q)
-	}
-	return
+// IsEmpty returns whether the Tree holds no intervals.
+func (t *Tree) IsEmpty() bool {
+	return t.Root == nil
 }
 
-// AdjustRanges fixes range fields for all Nodes in the Tree. This must be called
-// before Get or DoMatching* is used if fast insertion or deletion has been performed.
-func (t *Tree) AdjustRanges() {
-	if t.Root == nil {
-		return
+// Height returns the number of nodes on the longest path from the root of the Tree to a
+// leaf. An empty Tree has a height of zero.
+func (t *Tree) Height() int {
+	return t.Root.height()
+}
+
+// checkDepth panics if any path from the root of the Tree is longer than 4·⌈log₂(Count+1)⌉+8
+// nodes. A balanced tree never reaches this depth, so exceeding it indicates that the tree
+// has been corrupted, most likely by an inconsistent Comparable implementation. checkDepth
+// is only called when the package is built with the interval_debug tag.
+func (t *Tree) checkDepth() {
+	limit := 8
+	for n := t.Count; n > 0; n >>= 1 {
+		limit += 4
+	}
+	if t.Root.deeperThan(limit) {
+		panic("interval: comparator appears inconsistent")
 	}
-	t.Root.adjustRanges()
 }
 
-func (n *Node) adjustRanges() {
-	if n.Left != nil {
-		n.Left.adjustRanges()
+// deeperThan returns whether any path from n is longer than d nodes. The descent is bounded
+// by d, so deeperThan terminates even if the tree contains a cycle.
+func (n *Node) deeperThan(d int) bool {
+	if n == nil {
+		return false
 	}
-	if n.Right != nil {
-		n.Right.adjustRanges()
+	if d == 0 {
+		return true
 	}
-	n.adjustRange()
+	return n.Left.deeperThan(d-1) || n.Right.deeperThan(d-1)
 }
 
-// Insert inserts the Interface e into the Tree. Insertions may replace
-// existing stored intervals.
-func (t *Tree) Insert(e Interface, fast bool) (err error) {
-	if e.Start().Compare(e.End()) > 0 {
-		return ErrInvertedRange
+func (n *Node) height() int {
+	if n == nil {
+		return 0
 	}
-	var d int
-	t.Root, d = t.Root.insert(e, e.Start(), e.ID(), fast)
-	t.Count += d
-	t.Root.Color = llrb.Black
-	return
+	l, r := n.Left.height(), n.Right.height()
+	if l > r {
+		return l + 1
+	}
+	return r + 1
+}
+
+// BlackHeight returns the number of black nodes on each path from the root of the Tree to
+// a leaf. If the paths do not have the same number of black nodes, BlackHeight returns -1.
+func (t *Tree) BlackHeight() int {
+	return t.Root.blackHeight()
 }
 
-func (n *Node) insert(e Interface, min Comparable, id uintptr, fast bool) (root *Node, d int) {
+func (n *Node) blackHeight() int {
 	if n == nil {
-		return &Node{Elem: e, Range: e.NewMutable()}, 1
-	} else if n.Elem == nil {
-		n.Elem = e
-		if !fast {
-			n.adjustRange()
-		}
-		return n, 1
+		return 0
+	}
+	l, r := n.Left.blackHeight(), n.Right.blackHeight()
+	if l < 0 || l != r {
+		return -1
+	}
+	if n.Color == llrb.Black {
+		l++
 	}
+	return l
+}
 
-	if Mode == TD234 {
-		if n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
-			n.flipColors()
+// Validate checks that the Tree satisfies the LLRB and interval tree invariants: that
+// intervals are stored in sort order, that red links lean left and are not consecutive,
+// that every path from the root to a leaf has the same number of black links, and that
+// each Node's Range and Size correctly describe its subtree. Shared intervals must have the
+// start and end values of the Elem of their node and hold their place in the sort order. The first violation found
+// is returned as an error wrapping ErrInvalidTree, or ErrInvertedRange if a stored interval
+// is inverted. If fast insertion or deletion has been performed, AdjustRanges
+// must be called before Validate.
+func (t *Tree) Validate() error {
+	if t.Root == nil {
+		if t.Count != 0 {
+			return fmt.Errorf("%w: count %d for empty tree", ErrInvalidTree, t.Count)
 		}
+		return nil
 	}
-
-	switch c := min.Compare(n.Elem.Start()); {
-	case c == 0:
-		switch cid := id - n.Elem.ID(); {
-		case cid == 0:
-			n.Elem = e
-			if !fast {
-				n.Range.SetEnd(e.End())
-			}
-		case cid < 0:
-			n.Left, d = n.Left.insert(e, min, id, fast)
-		default:
-			n.Right, d = n.Right.insert(e, min, id, fast)
+	_, err := t.Root.validate(nil, nil)
+	if err != nil {
+		return err
+	}
+	if t.Root.Size != t.Count {
+		return fmt.Errorf("%w: count %d does not match size %d", ErrInvalidTree, t.Count, t.Root.Size)
+	}
+	var last Interface
+	t.Do(func(e Interface) (done bool) {
+		if last != nil && compare(last.Start(), last.ID(), e) >= 0 {
+			err = fmt.Errorf("%w: %v out of sort order", ErrInvalidTree, e)
+			return true
 		}
-	case c < 0:
-		n.Left, d = n.Left.insert(e, min, id, fast)
-	default:
-		n.Right, d = n.Right.insert(e, min, id, fast)
+		last = e
+		return
+	})
+	return err
+}
+
+// validate checks the invariants of the subtree rooted at n, with lo and hi the bounds
+// on start values imposed by the ancestors of n. It returns the black height of n.
+func (n *Node) validate(lo, hi Comparable) (black int, err error) {
+	if n == nil {
+		return 0, nil
+	}
+	if n.Elem == nil {
+		return 0, fmt.Errorf("%w: node without interval", ErrInvalidTree)
+	}
+	start := n.Elem.Start()
+	if start.Compare(n.Elem.End()) > 0 {
+		return 0, ErrInvertedRange
+	}
+	if (lo != nil && start.Compare(lo) < 0) || (hi != nil && start.Compare(hi) > 0) {
+		return 0, fmt.Errorf("%w: %v out of sort order", ErrInvalidTree, n.Elem)
+	}
+	if (Mode == BU23 && n.Right.color() == llrb.Red) ||
+		(Mode == TD234 && n.Right.color() == llrb.Red && n.Left.color() == llrb.Black) {
+		return 0, fmt.Errorf("%w: right-leaning red link at %v", ErrInvalidTree, n.Elem)
+	}
+	if n.color() == llrb.Red && n.Left.color() == llrb.Red {
+		return 0, fmt.Errorf("%w: consecutive red links at %v", ErrInvalidTree, n.Elem)
 	}
 
-	if n.Right.color() == llrb.Red && n.Left.color() == llrb.Black {
-		n = n.rotateLeft()
+	l, err := n.Left.validate(lo, start)
+	if err != nil {
+		return 0, err
 	}
-	if n.Left.color() == llrb.Red && n.Left.Left.color() == llrb.Red {
-		n = n.rotateRight()
+	r, err := n.Right.validate(start, hi)
+	if err != nil {
+		return 0, err
+	}
+	if l != r {
+		return 0, fmt.Errorf("%w: unbalanced black height at %v", ErrInvalidTree, n.Elem)
 	}
 
-	if Mode == BU23 {
-		if n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
-			n.flipColors()
+	if n.Left != nil {
+		start = n.Left.Range.Start()
+	}
+	if n.Range.Start().Compare(start) != 0 || n.Range.End().Compare(maxRange(n, n.Left, n.Right)) != 0 {
+		return 0, fmt.Errorf("%w: incorrect range at %v", ErrInvalidTree, n.Elem)
+	}
+	if n.Size != n.Left.size()+n.Right.size()+1+len(n.Shared) {
+		return 0, fmt.Errorf("%w: incorrect size at %v", ErrInvalidTree, n.Elem)
+	}
+	for _, e := range n.Shared {
+		if e.Start().Compare(n.Elem.Start()) != 0 || e.End().Compare(n.Elem.End()) != 0 {
+			return 0, fmt.Errorf("%w: shared %v differs from %v", ErrInvalidTree, e, n.Elem)
 		}
 	}
 
-	if !fast {
-		n.adjustRange()
+	if n.Color == llrb.Black {
+		l++
 	}
-	root = n
-
-	return
+	return l, nil
 }
 
-// DeleteMin deletes the left-most interval.
-func (t *Tree) DeleteMin(fast bool) {
+// Coverage returns the total length of the union of the intervals stored in the Tree, with
+// length used to measure the span between a start and an end value. Intervals are visited
+// in sort order and overlapping intervals are combined before being measured, so regions
+// covered by more than one interval are counted once. Intervals nested within another
+// interval make no contribution, and a zero-length interval that does not lie within
+// another interval contributes length(start, start).
+func (t *Tree) Coverage(length func(start, end Comparable) int) int {
 	if t.Root == nil {
+		return 0
+	}
+	var (
+		cov        int
+		start, end Comparable
+	)
+	t.Do(func(e Interface) (done bool) {
+		switch {
+		case start == nil:
+			start, end = e.Start(), e.End()
+		case e.Start().Compare(end) > 0:
+			cov += length(start, end)
+			start, end = e.Start(), e.End()
+		case e.End().Compare(end) > 0:
+			end = e.End()
+		}
+		return
+	})
+	return cov + length(start, end)
+}
+
+// DoClusters performs fn on each maximal cluster of overlapping intervals stored in the Tree,
+// in sort order. Intervals are visited in sort order and an interval joins the current cluster
+// if its start value is less than the largest end value of the intervals already in the
+// cluster, or equal to it if touching is true. Intervals nested within an interval of the
+// cluster therefore join it. A boolean is returned indicating whether the traversal was
+// interrupted by fn returning true. The slice passed to fn is only valid during the call.
+func (t *Tree) DoClusters(fn func(cluster []Interface) (done bool), touching bool) bool {
+	var (
+		cluster []Interface
+		end     Comparable
+	)
+	if t.Do(func(e Interface) (done bool) {
+		if len(cluster) != 0 {
+			c := e.Start().Compare(end)
+			if c > 0 || (c == 0 && !touching) {
+				if fn(cluster) {
+					return true
+				}
+				cluster = cluster[:0]
+			}
+		}
+		if len(cluster) == 0 || e.End().Compare(end) > 0 {
+			end = e.End()
+		}
+		cluster = append(cluster, e)
 		return
+	}) {
+		return true
 	}
-	var d int
-	t.Root, d = t.Root.deleteMin(fast)
-	t.Count += d
-	if t.Root == nil {
+	if len(cluster) != 0 {
+		return fn(cluster)
+	}
+	return false
+}
+
+// Gaps calls emit with the start and end values of each region within bound that is not
+// covered by an interval stored in the Tree, in ascending order. Stored intervals are visited
+// in sort order and overlapping intervals are combined, so a region is reported only if no
+// stored interval covers it. Regions between bound's start and the first covering interval,
+// and between the last covering interval and bound's end, are included. If emit returns true
+// the traversal is halted. ErrInvertedRange is returned if bound is inverted, and
+// ErrNilOverlapper is returned if bound is nil.
+func (t *Tree) Gaps(bound Range, emit func(start, end Comparable) (done bool)) error {
+	if bound == nil {
+		return ErrNilOverlapper
+	}
+	if bound.Start().Compare(bound.End()) > 0 {
+		return ErrInvertedRange
+	}
+	var (
+		pos    = bound.Start()
+		halted bool
+	)
+	t.Do(func(e Interface) (done bool) {
+		if e.Start().Compare(bound.End()) >= 0 {
+			return true
+		}
+		if e.End().Compare(pos) <= 0 {
+			return
+		}
+		if e.Start().Compare(pos) > 0 && emit(pos, e.Start()) {
+			halted = true
+			return true
+		}
+		pos = e.End()
 		return
+	})
+	if halted {
+		return nil
 	}
-	t.Root.Color = llrb.Black
+	if pos.Compare(bound.End()) < 0 {
+		emit(pos, bound.End())
+	}
+	return nil
 }
 
-func (n *Node) deleteMin(fast bool) (root *Node, d int) {
-	if n.Left == nil {
-		return nil, -1
+// MinGap returns the smallest positive gap between the end of an interval stored in the Tree
+// and the start of the next interval in sort order, with the flanking intervals and true.
+// The intervals are swept in sort order tracking the interval with the furthest end so far,
+// which is reported as before, so an interval nested within an earlier one does not hide the
+// earlier interval's end. The gap is measured as dist(before.End(), after.Start()).
+// Overlapping and abutting intervals, and any gap for which dist returns a value that is not
+// positive, are skipped. If no gap is found, ok is false. MinGap takes O(n) time.
+func (t *Tree) MinGap(dist func(a, b Comparable) float64) (gap float64, before, after Interface, ok bool) {
+	var last Interface
+	t.Do(func(e Interface) (done bool) {
+		if last == nil {
+			last = e
+			return
+		}
+		if e.Start().Compare(last.End()) > 0 {
+			if g := dist(last.End(), e.Start()); g > 0 && (!ok || g < gap) {
+				gap, before, after, ok = g, last, e, true
+			}
+		}
+		if e.End().Compare(last.End()) > 0 {
+			last = e
+		}
+		return
+	})
+	return gap, before, after, ok
+}
+
+// HasOverlap returns a pair of intervals stored in the Tree that overlap and true, or false if
+// the stored intervals are disjoint. The intervals are swept in sort order tracking the
+// interval with the furthest end so far, a, and the first interval b with a start value not
+// greater than the end of a and for which b.Overlap(a) is true is reported, so whether
+// abutting intervals overlap is determined by their Overlap method. HasOverlap takes O(n)
+// time.
+func (t *Tree) HasOverlap() (a, b Interface, ok bool) {
+	var last Interface
+	t.Do(func(e Interface) (done bool) {
+		if last != nil && e.Start().Compare(last.End()) <= 0 && e.Overlap(last) {
+			a, b, ok = last, e, true
+			return true
+		}
+		if last == nil || e.End().Compare(last.End()) > 0 {
+			last = e
+		}
+		return
+	})
+	return a, b, ok
+}
+
+// DepthProfile calls emit with the start and end values and the depth of each run of constant
+// coverage depth within [lo, hi), in ascending order, where the depth at a point is the number
+// of stored intervals containing it when treated as half-open. Adjacent runs always differ in
+// depth, and the runs together span [lo, hi), so uncovered regions are emitted with a depth of
+// zero. Only the intervals overlapping [lo, hi) are visited. If emit returns true the sweep is
+// halted. ErrInvertedRange is returned if lo is greater than hi.
+func (t *Tree) DepthProfile(lo, hi Comparable, emit func(start, end Comparable, depth int) (done bool)) error {
+	if lo.Compare(hi) > 0 {
+		return ErrInvertedRange
 	}
-	if n.Left.color() == llrb.Black && n.Left.Left.color() == llrb.Black {
-		n = n.moveRedLeft()
+	if lo.Compare(hi) == 0 {
+		return nil
 	}
-	n.Left, d = n.Left.deleteMin(fast)
-	if n.Left == nil {
-		n.Range.SetStart(n.Elem.Start())
+	var ev []depthEvent
+	t.Root.depthEvents(lo, hi, &ev)
+	sort.Sort(byPos(ev))
+
+	var (
+		start = lo
+		depth int
+		i     int
+	)
+	for ; i < len(ev) && ev[i].pos.Compare(lo) <= 0; i++ {
+		depth += ev[i].delta
+	}
+	for {
+		end, next := hi, depth
+		for i < len(ev) && ev[i].pos.Compare(hi) < 0 {
+			p := ev[i].pos
+			for ; i < len(ev) && ev[i].pos.Compare(p) == 0; i++ {
+				next += ev[i].delta
+			}
+			if next != depth {
+				end = p
+				break
+			}
+		}
+		if emit(start, end, depth) || end.Compare(hi) >= 0 {
+			return nil
+		}
+		start, depth = end, next
 	}
+}
 
-	root = n.fixUp(fast)
+// depthEvent is a change in coverage depth at a position.
+type depthEvent struct {
+	pos   Comparable
+	delta int
+}
 
-	return
+type byPos []depthEvent
+
+func (s byPos) Len() int           { return len(s) }
+func (s byPos) Less(i, j int) bool { return s[i].pos.Compare(s[j].pos) < 0 }
+func (s byPos) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// depthEvents appends the clipped start and end events of the non-empty intervals in the
+// subtree rooted at n that overlap [lo, hi) to ev.
+func (n *Node) depthEvents(lo, hi Comparable, ev *[]depthEvent) {
+	if n == nil || n.Range.Start().Compare(hi) >= 0 || n.Range.End().Compare(lo) <= 0 {
+		return
+	}
+	n.Left.depthEvents(lo, hi, ev)
+	if n.Elem.Start().Compare(hi) >= 0 {
+		return
+	}
+	n.each(func(e Interface) (done bool) {
+		s, end := e.Start(), e.End()
+		if s.Compare(end) >= 0 || end.Compare(lo) <= 0 {
+			return
+		}
+		if s.Compare(lo) < 0 {
+			s = lo
+		}
+		if end.Compare(hi) > 0 {
+			end = hi
+		}
+		*ev = append(*ev, depthEvent{pos: s, delta: 1}, depthEvent{pos: end, delta: -1})
+		return
+	})
+	n.Right.depthEvents(lo, hi, ev)
 }
 
-// DeleteMax deletes the right-most interval.
-func (t *Tree) DeleteMax(fast bool) {
+// Bounds returns the smallest start value and the largest end value of the intervals stored
+// in the Tree, and true. If the Tree is empty, nil values and false are returned. Bounds reads
+// the root's range, so AdjustRanges must be called before Bounds is used if fast insertion or
+// deletion has been performed.
+func (t *Tree) Bounds() (start, end Comparable, ok bool) {
 	if t.Root == nil {
+		return nil, nil, false
+	}
+	return t.Root.Range.Start(), t.Root.Range.End(), true
+}
+
+// Rebalance rebuilds the Tree from its stored intervals in O(n) time using the same
+// construction as NewFromSorted. The rebuilt tree is as close to perfectly balanced as the
+// red-black invariants allow, which may reduce its height after many deletions.
+func (t *Tree) Rebalance() {
+	t.mustBeWritable()
+	t.rebuild(t.Slice())
+}
+
+// Map returns a new Tree holding the results of applying fn to each interval stored in the
+// Tree, which is not altered. If any result has a start value greater than its end value,
+// ErrInvertedRange is returned, and if more than one result has the same start and ID
+// values, ErrDuplicate is returned rather than silently discarding all but one of them. The
+// new Tree is built in O(n) time when fn preserves sort order, and in O(n log n) time
+// otherwise.
+func (t *Tree) Map(fn func(Interface) Interface) (*Tree, error) {
+	elems := t.Slice()
+	for i, e := range elems {
+		e = fn(e)
+		if e.Start().Compare(e.End()) > 0 {
+			return nil, ErrInvertedRange
+		}
+		elems[i] = e
+	}
+	if !sort.IsSorted(byKey(elems)) {
+		sort.Stable(byKey(elems))
+	}
+	for i := 1; i < len(elems); i++ {
+		if compare(elems[i].Start(), elems[i].ID(), elems[i-1]) == 0 {
+			return nil, ErrDuplicate
+		}
+	}
+	return buildFrom(elems), nil
+}
+
+// buildFrom returns a new Tree holding elems, which may be in any order. If more than one
+// interval has the same start and ID values, only the last in elems is retained.
+func buildFrom(elems []Interface) *Tree {
+	if !sort.IsSorted(byKey(elems)) {
+		sort.Stable(byKey(elems))
+	}
+	w := 0
+	for _, e := range elems {
+		if w > 0 && compare(e.Start(), e.ID(), elems[w-1]) == 0 {
+			w--
+		}
+		elems[w] = e
+		w++
+	}
+	t := &Tree{}
+	t.rebuild(elems[:w])
+	return t
+}
+
+// Trim returns a new Tree holding the intervals stored in the Tree clipped to the closed range
+// [lo, hi]. Intervals lying entirely outside [lo, hi] are omitted, intervals lying entirely
+// within it are retained unaltered, and each interval extending beyond it is replaced by the
+// result of clip(e, lo, hi), which must return an interval with the ID of e and end values
+// within [lo, hi]. The receiver is not altered. If lo is greater than hi or a clipped interval
+// has a start value greater than its end value, ErrInvertedRange is returned.
+func (t *Tree) Trim(lo, hi Comparable, clip func(e Interface, lo, hi Comparable) Interface) (*Tree, error) {
+	if lo.Compare(hi) > 0 {
+		return nil, ErrInvertedRange
+	}
+	var (
+		elems []Interface
+		err   error
+	)
+	t.Root.trim(lo, hi, func(e Interface) (done bool) {
+		if e.Start().Compare(lo) < 0 || e.End().Compare(hi) > 0 {
+			e = clip(e, lo, hi)
+			if e.Start().Compare(e.End()) > 0 {
+				err = ErrInvertedRange
+				return true
+			}
+		}
+		elems = append(elems, e)
 		return
+	})
+	if err != nil {
+		return nil, err
 	}
-	var d int
-	t.Root, d = t.Root.deleteMax(fast)
-	t.Count += d
+	return buildFrom(elems), nil
+}
+func (n *Node) trim(lo, hi Comparable, fn Operation) (done bool) {
+	if n == nil || n.Range.Start().Compare(hi) > 0 || n.Range.End().Compare(lo) < 0 {
+		return
+	}
+	if n.Left.trim(lo, hi, fn) {
+		return true
+	}
+	if n.Elem.Start().Compare(hi) > 0 {
+		return
+	}
+	if n.Elem.End().Compare(lo) >= 0 && n.each(fn) {
+		return true
+	}
+	return n.Right.trim(lo, hi, fn)
+}
+
+// Filter deletes every interval stored in the Tree for which keep returns false, returning
+// the number of intervals deleted. If any interval is deleted, the Tree is rebuilt from the
+// kept intervals in O(n) time.
+func (t *Tree) Filter(keep func(Interface) bool) int {
+	t.mustBeWritable()
+	var (
+		elems = make([]Interface, 0, t.Count)
+		n     int
+	)
+	t.Do(func(e Interface) (done bool) {
+		if keep(e) {
+			elems = append(elems, e)
+		} else {
+			n++
+		}
+		return
+	})
+	if n != 0 {
+		t.rebuild(elems)
+	}
+	return n
+}
+
+// DeleteRange deletes every interval stored in the Tree with a start value within the closed
+// range [lo, hi], returning the number of intervals deleted. If any interval is deleted, the
+// Tree is rebuilt from the remaining intervals in O(n) time, avoiding the repeated descents
+// of deleting each interval in turn. If lo is greater than hi, ErrInvertedRange is returned
+// and the Tree is not altered.
+func (t *Tree) DeleteRange(lo, hi Comparable) (int, error) {
+	if err := t.writable(); err != nil {
+		return 0, err
+	}
+	if lo.Compare(hi) > 0 {
+		return 0, ErrInvertedRange
+	}
+	var (
+		elems = make([]Interface, 0, t.Count)
+		n     int
+	)
+	t.Do(func(e Interface) (done bool) {
+		if s := e.Start(); lo.Compare(s) <= 0 && s.Compare(hi) <= 0 {
+			n++
+		} else {
+			elems = append(elems, e)
+		}
+		return
+	})
+	if n != 0 {
+		t.rebuild(elems)
+	}
+	return n, nil
+}
+
+// HasDuplicateKeys returns whether any two intervals stored in the Tree have equal start
+// values according to Compare.
+func (t *Tree) HasDuplicateKeys() bool {
+	var last Interface
+	return t.Do(func(e Interface) (done bool) {
+		if last != nil && last.Start().Compare(e.Start()) == 0 {
+			return true
+		}
+		last = e
+		return
+	})
+}
+
+// DuplicateKeys returns the start values that are held by more than one interval stored in
+// the Tree, in sort order. Each duplicated start value is returned once.
+func (t *Tree) DuplicateKeys() []Comparable {
+	var (
+		keys []Comparable
+		last Interface
+		dup  bool
+	)
+	t.Do(func(e Interface) (done bool) {
+		if last != nil && last.Start().Compare(e.Start()) == 0 {
+			if !dup {
+				keys = append(keys, e.Start())
+			}
+			dup = true
+		} else {
+			dup = false
+		}
+		last = e
+		return
+	})
+	return keys
+}
+
+// Fix restores the sort order and ranges of the Tree after the start or end value of the
+// stored interval e has been altered. The stored interval with the ID of e is removed and e
+// is inserted in its correct position. Since the altered interval cannot be found by key,
+// Fix takes O(n) time. If no interval with the ID of e is stored in the Tree, ErrNotFound is
+// returned, if e is nil, ErrNilOverlapper is returned, and if e has a start value greater
+// than its end value, ErrInvertedRange is returned; in these cases the Tree is not altered.
+func (t *Tree) Fix(e Interface) error {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	if e == nil {
+		return ErrNilOverlapper
+	}
+	if e.Start().Compare(e.End()) > 0 {
+		return ErrInvertedRange
+	}
+	var (
+		id    = e.ID()
+		found bool
+		elems = make([]Interface, 0, t.Count)
+	)
+	t.Do(func(s Interface) (done bool) {
+		if s.ID() == id {
+			found = true
+		} else {
+			elems = append(elems, s)
+		}
+		return
+	})
+	if !found {
+		return ErrNotFound
+	}
+	t.rebuild(elems)
+	return t.Insert(e, false)
+}
+
+// FixAll restores the sort order and ranges of the Tree after the start or end values of
+// any number of stored intervals have been altered, by rebuilding the Tree from its stored
+// intervals.
+func (t *Tree) FixAll() {
+	t.mustBeWritable()
+	elems := t.Slice()
+	sort.Sort(byKey(elems))
+	t.rebuild(elems)
+}
+
+// Split divides the intervals stored in the Tree into two new trees; left holds the intervals
+// with a start value less than k and right holds those with a start value greater than or
+// equal to k. The new trees are built in O(n) time from the receiver's intervals in sort
+// order and the receiver is left empty.
+func (t *Tree) Split(k Comparable) (left, right *Tree) {
+	t.mustBeWritable()
+	elems := t.Slice()
+	i := sort.Search(len(elems), func(i int) bool { return elems[i].Start().Compare(k) >= 0 })
+	left, right = &Tree{}, &Tree{}
+	left.rebuild(elems[:i])
+	right.rebuild(elems[i:])
+	t.Clear()
+	return left, right
+}
+
+// byKey sorts intervals by start value, with ties broken by ID.
+type byKey []Interface
+
+func (s byKey) Len() int           { return len(s) }
+func (s byKey) Less(i, j int) bool { return compare(s[i].Start(), s[i].ID(), s[j]) < 0 }
+func (s byKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Slice returns a slice holding all intervals stored in the Tree in sort order.
+func (t *Tree) Slice() []Interface {
+	elems := make([]Interface, 0, t.Count)
+	t.Do(func(e Interface) (done bool) { elems = append(elems, e); return })
+	return elems
+}
+
+// SliceReverse returns a slice holding all intervals stored in the Tree in reverse sort order.
+func (t *Tree) SliceReverse() []Interface {
+	elems := make([]Interface, 0, t.Count)
+	t.DoReverse(func(e Interface) (done bool) { elems = append(elems, e); return })
+	return elems
+}
+
+// Clear removes all intervals from the Tree, leaving it ready for reuse. The nodes of a Tree
+// created by NewWithPool are returned to its pool.
+func (t *Tree) Clear() {
+	t.mustBeWritable()
+	t.env.putAll(t.Root)
+	t.Root, t.Count = nil, 0
+}
+
+// Clone returns a copy of the Tree with identical structure. Each Node and its Range are
+// newly allocated, so rebalancing either tree does not alter the other, but the stored
+// Interfaces are shared between the two trees. The clone of a Tree created by NewWeighted
+// maintains weights in the same way.
+func (t *Tree) Clone() *Tree {
+	c := &Tree{Count: t.Count}
+	if t.env != nil && (t.env.weighted || t.env.shared) {
+		c.env = &treeEnv{weighted: t.env.weighted, shared: t.env.shared}
+	}
+	c.Root = t.Root.clone(c.env)
+	return c
+}
+
+func (n *Node) clone(p *treeEnv) *Node {
+	if n == nil {
+		return nil
+	}
+	c := p.get(n.Elem)
+	c.Left, c.Right = n.Left.clone(p), n.Right.clone(p)
+	c.Color, c.Size = n.Color, n.Size
+	c.Weight = n.Weight
+	if n.Shared != nil {
+		c.Shared = append([]Interface(nil), n.Shared...)
+	}
+	c.Range.SetStart(n.Range.Start())
+	c.Range.SetEnd(n.Range.End())
+	return c
+}
+
+// Intersection returns the intervals stored in the Tree that overlap at least one interval
+// stored in u, in sort order and without duplicates. The smaller of the two trees is used to
+// probe the larger, so the overlap relation is assumed to be symmetric.
+func (t *Tree) Intersection(u *Tree) []Interface {
+	var o []Interface
+	if t.Len() <= u.Len() {
+		t.Do(func(e Interface) (done bool) {
+			if _, ok, _ := u.AnyOverlap(e); ok {
+				o = append(o, e)
+			}
+			return
+		})
+		return o
+	}
+	hit := make(map[uintptr]bool)
+	u.Do(func(q Interface) (done bool) {
+		t.DoMatching(func(e Interface) (done bool) { hit[e.ID()] = true; return }, q)
+		return
+	})
+	t.Do(func(e Interface) (done bool) {
+		if hit[e.ID()] {
+			o = append(o, e)
+		}
+		return
+	})
+	return o
+}
+
+// Difference returns the intervals stored in the Tree that do not overlap any interval
+// stored in u, in sort order.
+func (t *Tree) Difference(u *Tree) []Interface {
+	var o []Interface
+	t.Do(func(e Interface) (done bool) {
+		if _, ok, _ := u.AnyOverlap(e); !ok {
+			o = append(o, e)
+		}
+		return
+	})
+	return o
+}
+
+// JoinOverlapping calls emit for each pair of intervals x stored in a and y stored in b where
+// x overlaps y according to x.Overlap(). Both trees are swept once in sort order, keeping the
+// intervals of each tree that may still overlap later intervals of the other, so the work done
+// is proportional to the sizes of the trees and the number of intervals open at each start
+// value, rather than to the product of the sizes. The overlap relation is assumed to hold only
+// between intervals where neither ends before the other starts. Pairs are emitted in the order
+// the later-starting member of each pair is reached. A boolean is returned indicating whether
+// the sweep was halted by emit returning true.
+func JoinOverlapping(a, b *Tree, emit func(x, y Interface) (done bool)) bool {
+	var (
+		ca, cb       = a.Cursor(), b.Cursor()
+		x, okx       = ca.Next()
+		y, oky       = cb.Next()
+		openA, openB []Interface
+	)
+	for okx || oky {
+		if !oky || (okx && x.Start().Compare(y.Start()) <= 0) {
+			openB = expire(openB, x.Start())
+			for _, o := range openB {
+				if x.Overlap(o) && emit(x, o) {
+					return true
+				}
+			}
+			openA = append(openA, x)
+			x, okx = ca.Next()
+		} else {
+			openA = expire(openA, y.Start())
+			for _, o := range openA {
+				if o.Overlap(y) && emit(o, y) {
+					return true
+				}
+			}
+			openB = append(openB, y)
+			y, oky = cb.Next()
+		}
+	}
+	return false
+}
+
+// Diff returns the changes that transform the intervals stored in old into those stored in
+// new. Both trees are walked once together in sort order and intervals are matched by their
+// start and ID values. Intervals only in new are returned in added and intervals only in old
+// are returned in removed. An interval in old matched by one in new for which equal returns
+// false has changed, and is returned in removed with its replacement returned in added. Both
+// slices are in sort order. Diff takes O(n+m) time for trees holding n and m intervals.
+func Diff(old, new *Tree, equal func(a, b Interface) bool) (added, removed []Interface) {
+	var (
+		co, cn = old.Cursor(), new.Cursor()
+		a, oka = co.Next()
+		b, okb = cn.Next()
+	)
+	for oka || okb {
+		var c int
+		switch {
+		case !oka:
+			c = 1
+		case !okb:
+			c = -1
+		default:
+			c = compare(a.Start(), a.ID(), b)
+		}
+		switch {
+		case c < 0:
+			removed = append(removed, a)
+			a, oka = co.Next()
+		case c > 0:
+			added = append(added, b)
+			b, okb = cn.Next()
+		default:
+			if !equal(a, b) {
+				removed = append(removed, a)
+				added = append(added, b)
+			}
+			a, oka = co.Next()
+			b, okb = cn.Next()
+		}
+	}
+	return added, removed
+}
+
+// expire removes the intervals ending before p from open.
+func expire(open []Interface, p Comparable) []Interface {
+	w := 0
+	for _, e := range open {
+		if e.End().Compare(p) >= 0 {
+			open[w] = e
+			w++
+		}
+	}
+	return open[:w]
+}
+
+// Equal returns whether the Tree and u hold the same number of intervals and each pair of
+// intervals at the same position in sort order satisfies equal. The comparison halts at the
+// first mismatch. The shapes of the two trees are not compared.
+func (t *Tree) Equal(u *Tree, equal func(a, b Interface) bool) bool {
+	if t.Len() != u.Len() {
+		return false
+	}
+	tc, uc := t.Cursor(), u.Cursor()
+	for {
+		a, ok := tc.Next()
+		if !ok {
+			return true
+		}
+		b, _ := uc.Next()
+		if !equal(a, b) {
+			return false
+		}
+	}
+}
+
+// Get returns a slice of Interfaces that overlap q in the Tree according
+// to q.Overlap(). If q is nil, Get returns nil.
+func (t *Tree) Get(q Overlapper) []Interface {
+	return t.GetInto(q, nil)
+}
+
+// GetInto appends the Interfaces that overlap q in the Tree according to q.Overlap()
+// to dst and returns the extended slice. Reusing dst between calls avoids allocation
+// when the capacity of dst is sufficient. If q is nil, dst is returned unaltered.
+func (t *Tree) GetInto(q Overlapper, dst []Interface) []Interface {
+	if q == nil {
+		return dst
+	}
+	if debug {
+		t.checkDepth()
+	}
+	if t.Root != nil && q.Overlap(t.Root.Range) {
+		t.Root.doMatch(func(e Interface) (done bool) { dst = append(dst, e); return }, q)
+	}
+	return dst
+}
+
+// GetMulti returns a slice of the Interfaces that overlap any of the queries in qs in the
+// Tree according to Overlap(), in sort order and without duplicates. The Tree is traversed
+// once, with each subtree visited only by the queries that overlap its range, rather than
+// once per query. If a query is nil or implements Range and has a start value greater than
+// its end value, a *BatchError holding its index and ErrNilOverlapper or ErrInvertedRange is
+// returned.
+func (t *Tree) GetMulti(qs []Overlapper) ([]Interface, error) {
+	for i, q := range qs {
+		if err := checkQuery(q); err != nil {
+			return nil, &BatchError{Index: i, Err: err}
+		}
+	}
+	var o []Interface
+	t.Root.getMulti(qs, &o)
+	return o, nil
+}
+func (n *Node) getMulti(qs []Overlapper, o *[]Interface) {
+	if n == nil {
+		return
+	}
+	var active []Overlapper
+	for _, q := range qs {
+		if q.Overlap(n.Range) {
+			active = append(active, q)
+		}
+	}
+	if len(active) == 0 {
+		return
+	}
+	n.Left.getMulti(active, o)
+	for _, q := range active {
+		if q.Overlap(n.Elem) {
+			*o = append(append(*o, n.Elem), n.Shared...)
+			break
+		}
+	}
+	n.Right.getMulti(active, o)
+}
+
+// WeightOverlapping returns the sum of the weights of the Weighted intervals stored in the
+// Tree that overlap q according to q.Overlap(). If the Tree was created by NewWeighted and q
+// also implements Range, the summed weight of subtrees lying strictly within q is used without
+// visiting their nodes. Otherwise each overlapping interval is visited. If q is nil, zero is
+// returned.
+func (t *Tree) WeightOverlapping(q Overlapper) float64 {
+	if q == nil || t.Root == nil || !q.Overlap(t.Root.Range) {
+		return 0
+	}
+	if !t.Root.weighted {
+		var w float64
+		t.DoMatching(func(e Interface) (done bool) {
+			w += weightOf(e)
+			return
+		}, q)
+		return w
+	}
+	r, _ := q.(Range)
+	return t.Root.weightOverlapping(q, r)
+}
+func (n *Node) weightOverlapping(q Overlapper, r Range) (w float64) {
+	if r != nil && r.Start().Compare(n.Range.Start()) < 0 && n.Range.End().Compare(r.End()) < 0 {
+		return n.weight()
+	}
+	if n.Left != nil && q.Overlap(n.Left.Range) {
+		w += n.Left.weightOverlapping(q, r)
+	}
+	if q.Overlap(n.Elem) {
+		w += n.ownWeight()
+	}
+	if n.Right != nil && q.Overlap(n.Right.Range) {
+		w += n.Right.weightOverlapping(q, r)
+	}
+	return w
+}
+
+// GetN returns a slice of at most n Interfaces that overlap q in the Tree according to
+// q.Overlap(). The traversal halts once n matches have been found. The returned intervals
+// are the first n overlapping intervals in sort order. If q is nil, ErrNilOverlapper is
+// returned, and if q implements Range and has a start value greater than its end value,
+// ErrInvertedRange is returned.
+func (t *Tree) GetN(q Overlapper, n int) ([]Interface, error) {
+	if err := checkQuery(q); err != nil {
+		return nil, err
+	}
+	if n <= 0 || t.Root == nil || !q.Overlap(t.Root.Range) {
+		return nil, nil
+	}
+	var o []Interface
+	t.Root.doMatch(func(e Interface) (done bool) {
+		o = append(o, e)
+		return len(o) == n
+	}, q)
+	return o, nil
+}
+
+// Stab returns a slice of the Interfaces stored in the Tree that contain the point p, in sort
+// order. If closed is true, an interval contains p if start <= p <= end, so intervals with an
+// end point equal to p are included. Otherwise intervals are treated as half-open and an
+// interval contains p if start <= p < end, so zero-length intervals are never included.
+func (t *Tree) Stab(p Comparable, closed bool) []Interface {
+	var o []Interface
+	t.Root.stab(p, closed, &o)
+	return o
+}
+func (n *Node) stab(p Comparable, closed bool, o *[]Interface) {
+	if n == nil || n.Range.Start().Compare(p) > 0 || !contains(n.Range.End(), p, closed) {
+		return
+	}
+	n.Left.stab(p, closed, o)
+	if n.Elem.Start().Compare(p) > 0 {
+		return
+	}
+	if contains(n.Elem.End(), p, closed) {
+		*o = append(append(*o, n.Elem), n.Shared...)
+	}
+	n.Right.stab(p, closed, o)
+}
+
+// Depth returns the number of intervals stored in the Tree that contain the point p, with
+// containment defined as for Stab. Subtrees are pruned in the same way as for Stab, but the
+// containing intervals are only counted.
+func (t *Tree) Depth(p Comparable, closed bool) int {
+	return t.Root.depth(p, closed)
+}
+func (n *Node) depth(p Comparable, closed bool) int {
+	if n == nil || n.Range.Start().Compare(p) > 0 || !contains(n.Range.End(), p, closed) {
+		return 0
+	}
+	d := n.Left.depth(p, closed)
+	if n.Elem.Start().Compare(p) > 0 {
+		return d
+	}
+	if contains(n.Elem.End(), p, closed) {
+		d += 1 + len(n.Shared)
+	}
+	return d + n.Right.depth(p, closed)
+}
+
+// contains returns whether an interval with the given end value and a start value not
+// greater than p contains p.
+func contains(end, p Comparable, closed bool) bool {
+	if closed {
+		return end.Compare(p) >= 0
+	}
+	return end.Compare(p) > 0
+}
+
+// GetLimited returns a slice of Interfaces that overlap q in the Tree according to
+// q.Overlap(), visiting at most maxVisits nodes. The returned boolean is true if the
+// traversal was halted by the limit before all overlapping intervals could be found, in
+// which case the returned intervals are the overlapping intervals found in sort order
+// before the limit was reached. If q is nil, ErrNilOverlapper is returned, and if q
+// implements Range and has a start value greater than its end value, ErrInvertedRange is
+// returned.
+func (t *Tree) GetLimited(q Overlapper, maxVisits int) (o []Interface, truncated bool, err error) {
+	if err = checkQuery(q); err != nil {
+		return nil, false, err
+	}
+	if t.Root == nil || !q.Overlap(t.Root.Range) {
+		return nil, false, nil
+	}
+	truncated = t.Root.getLimited(q, &maxVisits, &o)
+	return o, truncated, nil
+}
+func (n *Node) getLimited(q Overlapper, visits *int, o *[]Interface) (truncated bool) {
+	if *visits <= 0 {
+		return true
+	}
+	*visits--
+	if n.Left != nil && q.Overlap(n.Left.Range) {
+		if n.Left.getLimited(q, visits, o) {
+			return true
+		}
+	}
+	if q.Overlap(n.Elem) {
+		*o = append(append(*o, n.Elem), n.Shared...)
+	}
+	if n.Right != nil && q.Overlap(n.Right.Range) {
+		return n.Right.getLimited(q, visits, o)
+	}
+	return false
+}
+
+// GetLastN returns a slice of at most n Interfaces that overlap q in the Tree according to
+// q.Overlap(), in reverse sort order. The traversal halts once n matches have been found. The
+// returned intervals are the last n overlapping intervals in sort order. If q is nil,
+// ErrNilOverlapper is returned, and if q implements Range and has a start value greater than
+// its end value, ErrInvertedRange is returned.
+func (t *Tree) GetLastN(q Overlapper, n int) ([]Interface, error) {
+	if err := checkQuery(q); err != nil {
+		return nil, err
+	}
+	if n <= 0 || t.Root == nil || !q.Overlap(t.Root.Range) {
+		return nil, nil
+	}
+	var o []Interface
+	t.Root.doMatchReverse(func(e Interface) (done bool) {
+		o = append(o, e)
+		return len(o) == n
+	}, q)
+	return o, nil
+}
+
+// Contained returns a slice of the Interfaces stored in the Tree that lie entirely within q,
+// that is with start and end values in [q.Start(), q.End()], in sort order. If q is nil,
+// Contained returns nil.
+func (t *Tree) Contained(q Range) []Interface {
+	if q == nil {
+		return nil
+	}
+	var o []Interface
+	t.Root.contained(q, &o)
+	return o
+}
+func (n *Node) contained(q Range, o *[]Interface) {
+	if n == nil || n.Range.End().Compare(q.Start()) < 0 || n.Range.Start().Compare(q.End()) > 0 {
+		return
+	}
+	n.Left.contained(q, o)
+	start := n.Elem.Start()
+	if start.Compare(q.End()) > 0 {
+		return
+	}
+	if start.Compare(q.Start()) >= 0 && n.Elem.End().Compare(q.End()) <= 0 {
+		*o = append(append(*o, n.Elem), n.Shared...)
+	}
+	n.Right.contained(q, o)
+}
+
+// Containing returns a slice of the Interfaces stored in the Tree that entirely enclose q,
+// that is with start values not greater than q.Start() and end values not less than q.End(),
+// in sort order. If q is nil, Containing returns nil.
+func (t *Tree) Containing(q Range) []Interface {
+	if q == nil {
+		return nil
+	}
+	var o []Interface
+	t.Root.containing(q, &o)
+	return o
+}
+func (n *Node) containing(q Range, o *[]Interface) {
+	if n == nil || n.Range.Start().Compare(q.Start()) > 0 || n.Range.End().Compare(q.End()) < 0 {
+		return
+	}
+	n.Left.containing(q, o)
+	if n.Elem.Start().Compare(q.Start()) > 0 {
+		return
+	}
+	if n.Elem.End().Compare(q.End()) >= 0 {
+		*o = append(append(*o, n.Elem), n.Shared...)
+	}
+	n.Right.containing(q, o)
+}
+
+// CountOverlaps returns the number of intervals stored in the Tree that overlap q according
+// to q.Overlap(). If q is nil, ErrNilOverlapper is returned, and if q implements Range and
+// has a start value greater than its end value, ErrInvertedRange is returned.
+func (t *Tree) CountOverlaps(q Overlapper) (int, error) {
+	if err := checkQuery(q); err != nil {
+		return 0, err
+	}
+	var n int
+	if t.Root != nil && q.Overlap(t.Root.Range) {
+		t.Root.doMatch(func(Interface) (done bool) { n++; return }, q)
+	}
+	return n, nil
+}
+
+// AnyOverlap returns an Interface stored in the Tree that overlaps q according to q.Overlap(),
+// and a boolean indicating whether any such interval was found. The traversal halts at the
+// first match, so AnyOverlap is cheaper than Get when only one overlapping interval is needed.
+// If q is nil, ErrNilOverlapper is returned, and if q implements Range and has a start value
+// greater than its end value, ErrInvertedRange is returned.
+func (t *Tree) AnyOverlap(q Overlapper) (o Interface, ok bool, err error) {
+	if err = checkQuery(q); err != nil {
+		return nil, false, err
+	}
+	if t.Root == nil || !q.Overlap(t.Root.Range) {
+		return nil, false, nil
+	}
+	n := t.Root.anyOverlap(q)
+	if n == nil {
+		return nil, false, nil
+	}
+	return n.Elem, true, nil
+}
+
+// checkQuery returns ErrNilOverlapper if q is nil, and ErrInvertedRange if q implements Range
+// and has a start value greater than its end value.
+func checkQuery(q Overlapper) error {
+	if q == nil {
+		return ErrNilOverlapper
+	}
+	if r, ok := q.(Range); ok && r.Start().Compare(r.End()) > 0 {
+		return ErrInvertedRange
+	}
+	return nil
+}
+
+func (n *Node) anyOverlap(q Overlapper) *Node {
+	if q.Overlap(n.Elem) {
+		return n
+	}
+	if n.Left != nil && q.Overlap(n.Left.Range) {
+		if m := n.Left.anyOverlap(q); m != nil {
+			return m
+		}
+	}
+	if n.Right != nil && q.Overlap(n.Right.Range) {
+		return n.Right.anyOverlap(q)
+	}
+	return nil
+}
+
+// FirstOverlap returns the first Interface in sort order stored in the Tree that overlaps q
+// according to q.Overlap(), and a boolean indicating whether any such interval was found.
+// Unlike AnyOverlap, the returned interval is always the left-most overlapping interval.
+func (t *Tree) FirstOverlap(q Overlapper) (o Interface, ok bool) {
+	if q == nil || t.Root == nil || !q.Overlap(t.Root.Range) {
+		return nil, false
+	}
+	n := t.Root.firstOverlap(q)
+	if n == nil {
+		return nil, false
+	}
+	return n.Elem, true
+}
+
+func (n *Node) firstOverlap(q Overlapper) *Node {
+	if n.Left != nil && q.Overlap(n.Left.Range) {
+		if m := n.Left.firstOverlap(q); m != nil {
+			return m
+		}
+	}
+	if q.Overlap(n.Elem) {
+		return n
+	}
+	if n.Right != nil && q.Overlap(n.Right.Range) {
+		return n.Right.firstOverlap(q)
+	}
+	return nil
+}
+
+// AdjustRanges fixes range fields for all Nodes in the Tree. This must be called
+// before Get or DoMatching* is used if fast insertion or deletion has been performed.
+func (t *Tree) AdjustRanges() {
+	t.mustBeWritable()
+	if t.Root == nil {
+		return
+	}
+	t.Root.adjustRanges()
+}
+
+func (n *Node) adjustRanges() {
+	if n.Left != nil {
+		n.Left.adjustRanges()
+	}
+	if n.Right != nil {
+		n.Right.adjustRanges()
+	}
+	n.adjustRange()
+}
+
+// Insert inserts the Interface e into the Tree. Insertions may replace
+// existing stored intervals. If e is nil, ErrNilOverlapper is returned.
+func (t *Tree) Insert(e Interface, fast bool) error {
+	_, err := t.InsertN(e, fast)
+	return err
+}
+
+// InsertN inserts the Interface e into the Tree in the same way as Insert, returning the
+// change in the number of stored intervals. The change is 1 if e was given a new node and 0
+// if e replaced a stored interval with the same start and ID values.
+func (t *Tree) InsertN(e Interface, fast bool) (d int, err error) {
+	if err = t.writable(); err != nil {
+		return 0, err
+	}
+	if e == nil {
+		return 0, ErrNilOverlapper
+	}
+	if e.Start().Compare(e.End()) > 0 {
+		return 0, ErrInvertedRange
+	}
+	if debug {
+		err = CheckMutable(e)
+		if err != nil {
+			return 0, err
+		}
+	}
+	rest, d, ok := t.splitRun(e)
+	if ok {
+		return 0, nil
+	}
+	var n int
+	t.Root, n = t.Root.insert(e, e.Start(), e.ID(), fast, t.env)
+	t.Count += n
+	t.Root.Color = llrb.Black
+	t.rehome(rest, fast)
+	if debug {
+		t.checkDepth()
+	}
+	return d + n, nil
+}
+
+// splitRun prepares for the insertion of e when the key of e falls within a run of intervals
+// held by a node with Shared intervals. If e has the key of a Shared interval and the same end
+// value, e replaces it in place and ok is returned true. Otherwise the intervals of the run
+// sorting after e are removed from the Tree and returned in rest, so that e may be given its
+// own node, and d is -1 if an interval with the key of e was removed with them.
+func (t *Tree) splitRun(e Interface) (rest []Interface, d int, ok bool) {
+	if t.env == nil || !t.env.shared {
+		return nil, 0, false
+	}
+	m, id := e.Start(), e.ID()
+	n := t.Root.floor(m, id)
+	if n == nil || len(n.Shared) == 0 {
+		return nil, 0, false
+	}
+	sameEnd := e.End().Compare(n.Elem.End()) == 0
+	i := n.sharedIndex(m, id)
+	if i == len(n.Shared) || (sameEnd && compare(m, id, n.Elem) == 0) {
+		return nil, 0, false
+	}
+	if compare(m, id, n.Shared[i]) == 0 {
+		if sameEnd {
+			n.Shared[i] = e
+			t.Root.reweigh(n.Elem.Start(), n.Elem.ID())
+			return nil, 0, true
+		}
+		n.Shared = append(n.Shared[:i:i], n.Shared[i+1:]...)
+		t.Count--
+		d = -1
+	}
+	rest, n.Shared = n.Shared[i:], n.Shared[:i:i]
+	t.Count -= len(rest)
+	t.Root.reweigh(n.Elem.Start(), n.Elem.ID())
+	return rest, d, false
+}
+
+// rehome stores the intervals in run, which have the same start and end values and are in
+// sort order, in a single node.
+func (t *Tree) rehome(run []Interface, fast bool) {
+	if len(run) == 0 {
+		return
+	}
+	h := run[0]
+	var d int
+	t.Root, d = t.Root.insert(h, h.Start(), h.ID(), fast, t.env)
+	t.Root.Color = llrb.Black
+	n := t.Root.search(h.Start(), h.ID())
+	n.Shared = run[1:]
+	t.Root.reweigh(h.Start(), h.ID())
+	t.Count += d + len(run) - 1
+}
+
+func (n *Node) insert(e Interface, min Comparable, id uintptr, fast bool, p *treeEnv) (root *Node, d int) {
+	if n == nil {
+		return p.get(e), 1
+	}
+
+	if Mode == TD234 {
+		if n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
+			n.flipColors(p)
+		}
+	}
+
+	switch c := min.Compare(n.Elem.Start()); {
+	case c == 0:
+		switch eid := n.Elem.ID(); {
+		case id == eid:
+			n.Elem = e
+			if !fast {
+				n.Range.SetEnd(e.End())
+			}
+		case id < eid:
+			n.Left, d = n.Left.insert(e, min, id, fast, p)
+		default:
+			n.Right, d = n.Right.insert(e, min, id, fast, p)
+		}
+	case c < 0:
+		n.Left, d = n.Left.insert(e, min, id, fast, p)
+	default:
+		n.Right, d = n.Right.insert(e, min, id, fast, p)
+	}
+	n.adjustSize()
+
+	if n.Right.color() == llrb.Red && n.Left.color() == llrb.Black {
+		n = n.rotateLeft(p)
+	}
+	if n.Left.color() == llrb.Red && n.Left.Left.color() == llrb.Red {
+		n = n.rotateRight(p)
+	}
+
+	if Mode == BU23 {
+		if n.Left.color() == llrb.Red && n.Right.color() == llrb.Red {
+			n.flipColors(p)
+		}
+	}
+
+	if !fast {
+		n.adjustRange()
+	}
+	root = n
+
+	return
+}
+
+// A BatchError is returned by InsertBatch when an interval in the batch is invalid.
+type BatchError struct {
+	Index int   // Index of the invalid interval in the batch.
+	Err   error // Reason the interval is invalid.
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("interval: batch element %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the reason the interval is invalid.
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// InsertShared inserts the Interface e into the Tree. If a stored interval s that overlaps e
+// according to e.Overlap() satisfies equalKey(s, e), and no other stored interval sorts
+// between s and e, e is added to the Shared intervals of the node holding s rather than being
+// given a node of its own; otherwise e is inserted as if by Insert. equalKey must only return
+// true for intervals with equal start and end values. Shared intervals hold their place in
+// the sort order, so they are returned by Get, the Do methods, the cursors and the order
+// statistic methods in the same way as other intervals, and are counted by Len. If an interval
+// with the start and ID values of e is already stored, ErrDuplicate is returned and the Tree is
+// not altered. Operations that rebuild the Tree, such as Rebalance and Split, store each
+// interval in its own node.
+func (t *Tree) InsertShared(e Interface, equalKey func(a, b Interface) bool, fast bool) error {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	if e == nil {
+		return ErrNilOverlapper
+	}
+	if e.Start().Compare(e.End()) > 0 {
+		return ErrInvertedRange
+	}
+	m, id := e.Start(), e.ID()
+	if f, _ := t.Floor(e); f != nil && compare(m, id, f) == 0 {
+		return ErrDuplicate
+	}
+	var s Interface
+	t.DoMatching(func(o Interface) (done bool) {
+		if equalKey(o, e) {
+			s = o
+			return true
+		}
+		return
+	}, e)
+	if s == nil {
+		return t.Insert(e, fast)
+	}
+	n := t.Root.floor(s.Start(), s.ID())
+	if compare(m, id, n.Elem) > 0 {
+		if t.Root.floor(m, id) != n {
+			return t.Insert(e, fast)
+		}
+		i := n.sharedIndex(m, id)
+		n.Shared = append(n.Shared, nil)
+		copy(n.Shared[i+1:], n.Shared[i:])
+		n.Shared[i] = e
+	} else {
+		if p, _ := t.Predecessor(n.Elem); p != nil && compare(m, id, p) < 0 {
+			return t.Insert(e, fast)
+		}
+		n.Shared = append([]Interface{n.Elem}, n.Shared...)
+		n.Elem = e
+	}
+	if t.env == nil {
+		t.env = &treeEnv{}
+	}
+	t.env.shared = true
+	t.Root.reweigh(n.Elem.Start(), n.Elem.ID())
+	t.Count++
+	return nil
+}
+
+// InsertBatch inserts all the Interfaces in elems into the Tree. Every interval is validated
+// before the Tree is altered, so either all of elems are inserted or none are. If an interval
+// is nil or has a start value greater than its end value, a *BatchError holding its index and
+// ErrNilOverlapper or ErrInvertedRange is returned. An error returned by insertion is also
+// returned as a *BatchError.
+func (t *Tree) InsertBatch(elems []Interface, fast bool) error {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	for i, e := range elems {
+		if e == nil {
+			return &BatchError{Index: i, Err: ErrNilOverlapper}
+		}
+		if e.Start().Compare(e.End()) > 0 {
+			return &BatchError{Index: i, Err: ErrInvertedRange}
+		}
+		if debug {
+			if err := CheckMutable(e); err != nil {
+				return &BatchError{Index: i, Err: err}
+			}
+		}
+	}
+	for i, e := range elems {
+		if err := t.Insert(e, fast); err != nil {
+			return &BatchError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// InsertOrReplace inserts the Interface e into the Tree unless a stored interval s that
+// overlaps e according to e.Overlap() satisfies equal(s, e), in which case s is replaced by e.
+// The returned boolean indicates whether a stored interval was replaced. If e has the same
+// start, end and ID values as the interval it replaces, the structure of the Tree is not
+// altered. If e is nil, ErrNilOverlapper is returned.
+func (t *Tree) InsertOrReplace(e Interface, equal func(a, b Interface) bool, fast bool) (replaced bool, err error) {
+	if err = t.writable(); err != nil {
+		return false, err
+	}
+	if e == nil {
+		return false, ErrNilOverlapper
+	}
+	if e.Start().Compare(e.End()) > 0 {
+		return false, ErrInvertedRange
+	}
+	var old Interface
+	t.DoMatching(func(s Interface) (done bool) {
+		if equal(s, e) {
+			old = s
+			return true
+		}
+		return
+	}, e)
+	if old != nil {
+		if t.Replace(old, e) == nil {
+			return true, nil
+		}
+		err = t.Delete(old, fast)
+		if err != nil {
+			return false, err
+		}
+		replaced = true
+	}
+	return replaced, t.Insert(e, fast)
+}
+
+// Replace replaces the stored interval old with new without altering the structure of the
+// Tree. The start, end and ID values of new must be equal to those of old, otherwise
+// ErrMismatchedKey is returned. If old is not stored in the Tree, ErrNotFound is returned.
+// If either old or new is nil, ErrNilOverlapper is returned.
+func (t *Tree) Replace(old, new Interface) error {
+	if err := t.writable(); err != nil {
+		return err
+	}
+	if old == nil || new == nil {
+		return ErrNilOverlapper
+	}
+	if old.Start().Compare(new.Start()) != 0 || old.End().Compare(new.End()) != 0 || old.ID() != new.ID() {
+		return ErrMismatchedKey
+	}
+	n := t.Root.search(old.Start(), old.ID())
+	if n == nil {
+		return ErrNotFound
+	}
+	w := weightOf(n.Elem)
+	n.Elem = new
+	if weightOf(new) != w {
+		t.Root.reweigh(old.Start(), old.ID())
+	}
+	return nil
+}
+
+// reweigh recalculates the Size and Weight of the nodes on the path from n to the node
+// holding the interval with start value m and ID id.
+func (n *Node) reweigh(m Comparable, id uintptr) {
+	if n == nil {
+		return
+	}
+	c := m.Compare(n.Elem.Start())
+	switch {
+	case c == 0 && id == n.Elem.ID():
+	case c < 0 || (c == 0 && id < n.Elem.ID()):
+		n.Left.reweigh(m, id)
+	default:
+		n.Right.reweigh(m, id)
+	}
+	n.adjustSize()
+}
+
+// search returns the node holding the interval with start value m and ID id.
+func (n *Node) search(m Comparable, id uintptr) *Node {
+	for n != nil {
+		c := m.Compare(n.Elem.Start())
+		switch {
+		case c == 0 && id == n.Elem.ID():
+			return n
+		case c < 0 || (c == 0 && id < n.Elem.ID()):
+			n = n.Left
+		default:
+			n = n.Right
+		}
+	}
+	return nil
+}
+
+// FindExact returns an interval stored in the Tree whose start and end values are equal to
+// start and end, and true. If no such interval exists, nil and false are returned. Subtrees
+// whose ranges cannot contain the interval are not searched.
+func (t *Tree) FindExact(start, end Comparable) (o Interface, ok bool) {
+	n := t.Root.findExact(start, end)
+	if n == nil {
+		return nil, false
+	}
+	return n.Elem, true
+}
+func (n *Node) findExact(start, end Comparable) *Node {
+	if n == nil || start.Compare(n.Range.Start()) < 0 || end.Compare(n.Range.End()) > 0 {
+		return nil
+	}
+	switch c := start.Compare(n.Elem.Start()); {
+	case c < 0:
+		return n.Left.findExact(start, end)
+	case c > 0:
+		return n.Right.findExact(start, end)
+	}
+	if end.Compare(n.Elem.End()) == 0 {
+		return n
+	}
+	if l := n.Left.findExact(start, end); l != nil {
+		return l
+	}
+	return n.Right.findExact(start, end)
+}
+
+// Merge inserts all intervals stored in u into the Tree. Intervals in u replace intervals in
+// the Tree as they would if inserted with Insert. The intervals are shared between the two
+// trees, and u is not altered.
+func (t *Tree) Merge(u *Tree, fast bool) {
+	t.mustBeWritable()
+	if t == u {
+		return
+	}
+	u.Do(func(e Interface) (done bool) {
+		t.InsertN(e, fast)
+		return
+	})
+}
+
+// Coalesce replaces each run of overlapping or abutting intervals stored in the Tree with
+// a single interval. Intervals are considered in sort order, and an interval whose start
+// value is not greater than the end value of the preceding, possibly already joined,
+// interval is combined with it by calling join. The intervals returned by join must not
+// have a start value greater than their end value. The Tree is rebuilt from the resulting
+// intervals in O(n) time and the number of joins performed is returned.
+func (t *Tree) Coalesce(join func(a, b Interface) Interface) int {
+	t.mustBeWritable()
+	if t.Root == nil {
+		return 0
+	}
+	var (
+		n     int
+		elems = make([]Interface, 0, t.Count)
+	)
+	t.Do(func(e Interface) (done bool) {
+		if len(elems) != 0 {
+			if last := elems[len(elems)-1]; e.Start().Compare(last.End()) <= 0 {
+				elems[len(elems)-1] = join(last, e)
+				n++
+				return
+			}
+		}
+		elems = append(elems, e)
+		return
+	})
+	if n == 0 {
+		return 0
+	}
+	t.rebuild(elems)
+	return n
+}
+
+// DeleteMin deletes the left-most interval.
+func (t *Tree) DeleteMin(fast bool) {
+	t.mustBeWritable()
+	if t.Root == nil {
+		return
+	}
+	var d int
+	t.Root, d = t.Root.deleteMin(fast, t.env)
+	t.Count += d
+	if t.Root == nil {
+		return
+	}
+	t.Root.Color = llrb.Black
+}
+
+func (n *Node) deleteMin(fast bool, p *treeEnv) (root *Node, d int) {
+	if n.Left == nil {
+		if len(n.Shared) != 0 {
+			n.promote()
+			return n, -1
+		}
+		p.put(n)
+		return nil, -1
+	}
+	if n.Left.color() == llrb.Black && n.Left.Left.color() == llrb.Black {
+		n = n.moveRedLeft(p)
+	}
+	n.Left, d = n.Left.deleteMin(fast, p)
+	if n.Left == nil {
+		n.Range.SetStart(n.Elem.Start())
+	}
+
+	root = n.fixUp(fast, p)
+
+	return
+}
+
+// DeleteMax deletes the right-most interval.
+func (t *Tree) DeleteMax(fast bool) {
+	t.mustBeWritable()
+	if t.Root == nil {
+		return
+	}
+	var d int
+	t.Root, d = t.Root.deleteMax(fast, t.env)
+	t.Count += d
+	if t.Root == nil {
+		return
+	}
+	t.Root.Color = llrb.Black
+}
+
+func (n *Node) deleteMax(fast bool, p *treeEnv) (root *Node, d int) {
+	if n.Left != nil && n.Left.color() == llrb.Red {
+		n = n.rotateRight(p)
+	}
+	if n.Right == nil {
+		if k := len(n.Shared); k != 0 {
+			n.Shared = n.Shared[:k-1]
+			n.adjustSize()
+			return n, -1
+		}
+		p.put(n)
+		return nil, -1
+	}
+	if n.Right.color() == llrb.Black && n.Right.Left.color() == llrb.Black {
+		n = n.moveRedRight(p)
+	}
+	n.Right, d = n.Right.deleteMax(fast, p)
+	if n.Right == nil {
+		n.Range.SetEnd(n.Elem.End())
+	}
+
+	root = n.fixUp(fast, p)
+
+	return
+}
+
+// PopMin deletes the left-most interval and returns it and true, or nil and false if the
+// Tree is empty. The interval is found and deleted in a single descent of the Tree.
+func (t *Tree) PopMin(fast bool) (Interface, bool) {
+	t.mustBeWritable()
+	if t.Root == nil {
+		return nil, false
+	}
+	var e Interface
+	t.Root, e = t.Root.popMin(fast, t.env)
+	t.Count--
+	if t.Root != nil {
+		t.Root.Color = llrb.Black
+	}
+	return e, true
+}
+
+func (n *Node) popMin(fast bool, p *treeEnv) (root *Node, e Interface) {
+	if n.Left == nil {
+		e = n.Elem
+		if len(n.Shared) != 0 {
+			n.promote()
+			return n, e
+		}
+		p.put(n)
+		return nil, e
+	}
+	if n.Left.color() == llrb.Black && n.Left.Left.color() == llrb.Black {
+		n = n.moveRedLeft(p)
+	}
+	n.Left, e = n.Left.popMin(fast, p)
+	if n.Left == nil {
+		n.Range.SetStart(n.Elem.Start())
+	}
+
+	root = n.fixUp(fast, p)
+
+	return
+}
+
+// PopMax deletes the right-most interval and returns it and true, or nil and false if the
+// Tree is empty. The interval is found and deleted in a single descent of the Tree.
+func (t *Tree) PopMax(fast bool) (Interface, bool) {
+	t.mustBeWritable()
 	if t.Root == nil {
-		return
+		return nil, false
 	}
-	t.Root.Color = llrb.Black
+	var e Interface
+	t.Root, e = t.Root.popMax(fast, t.env)
+	t.Count--
+	if t.Root != nil {
+		t.Root.Color = llrb.Black
+	}
+	return e, true
 }
 
-func (n *Node) deleteMax(fast bool) (root *Node, d int) {
+func (n *Node) popMax(fast bool, p *treeEnv) (root *Node, e Interface) {
 	if n.Left != nil && n.Left.color() == llrb.Red {
-		n = n.rotateRight()
+		n = n.rotateRight(p)
 	}
 	if n.Right == nil {
-		return nil, -1
+		if k := len(n.Shared); k != 0 {
+			e, n.Shared = n.Shared[k-1], n.Shared[:k-1]
+			n.adjustSize()
+			return n, e
+		}
+		e = n.Elem
+		p.put(n)
+		return nil, e
 	}
 	if n.Right.color() == llrb.Black && n.Right.Left.color() == llrb.Black {
-		n = n.moveRedRight()
+		n = n.moveRedRight(p)
 	}
-	n.Right, d = n.Right.deleteMax(fast)
+	n.Right, e = n.Right.popMax(fast, p)
 	if n.Right == nil {
 		n.Range.SetEnd(n.Elem.End())
 	}
 
-	root = n.fixUp(fast)
+	root = n.fixUp(fast, p)
 
 	return
 }
 
-// Delete deletes the element e if it exists in the Tree.
+// Delete deletes the element e if it exists in the Tree. If e is nil, ErrNilOverlapper is
+// returned. Stored intervals are found by start and ID values, not by e.Overlap(), so
+// zero-width intervals are deleted in the same way as other intervals.
 func (t *Tree) Delete(e Interface, fast bool) (err error) {
+	if err = t.writable(); err != nil {
+		return err
+	}
+	if e == nil {
+		return ErrNilOverlapper
+	}
 	if e.Start().Compare(e.End()) > 0 {
 		return ErrInvertedRange
 	}
-	if t.Root == nil || !e.Overlap(t.Root.Range) {
+	if t.Root == nil || e.Start().Compare(t.Root.Range.Start()) < 0 || e.Start().Compare(t.Root.Range.End()) > 0 {
 		return
 	}
 	var d int
-	t.Root, d = t.Root.delete(e.Start(), e.ID(), fast)
+	t.Root, d = t.Root.delete(e.Start(), e.ID(), fast, t.env)
 	t.Count += d
 	if t.Root == nil {
 		return
 	}
 	t.Root.Color = llrb.Black
+	if debug {
+		t.checkDepth()
+	}
 	return
 }
 
-func (n *Node) delete(min Comparable, id uintptr, fast bool) (root *Node, d int) {
-	if p := min.Compare(n.Elem.Start()); p < 0 || (p == 0 && id < n.Elem.ID()) {
+func (n *Node) delete(min Comparable, id uintptr, fast bool, p *treeEnv) (root *Node, d int) {
+	if c := min.Compare(n.Elem.Start()); c < 0 || (c == 0 && id < n.Elem.ID()) {
 		if n.Left != nil {
 			if n.Left.color() == llrb.Black && n.Left.Left.color() == llrb.Black {
-				n = n.moveRedLeft()
+				n = n.moveRedLeft(p)
 			}
-			n.Left, d = n.Left.delete(min, id, fast)
+			n.Left, d = n.Left.delete(min, id, fast, p)
 			if n.Left == nil {
 				n.Range.SetStart(n.Elem.Start())
 			}
 		}
 	} else {
 		if n.Left.color() == llrb.Red {
-			n = n.rotateRight()
+			n = n.rotateRight(p)
 		}
-		if n.Right == nil && id == n.Elem.ID() {
+		switch c := compare(min, id, n.Elem); {
+		case c == 0 && len(n.Shared) != 0:
+			n.promote()
+			d = -1
+			return n.fixUp(fast, p), d
+		case c > 0 && n.deleteShared(min, id):
+			d = -1
+			return n.fixUp(fast, p), d
+		case c == 0 && n.Right == nil:
+			p.put(n)
 			return nil, -1
 		}
 		if n.Right != nil {
 			if n.Right.color() == llrb.Black && n.Right.Left.color() == llrb.Black {
-				n = n.moveRedRight()
+				n = n.moveRedRight(p)
 			}
-			if id == n.Elem.ID() {
-				n.Elem = n.Right.min().Elem
-				n.Right, d = n.Right.deleteMin(fast)
+			if compare(min, id, n.Elem) == 0 {
+				d = -1
+				m := n.Right.min()
+				n.Elem, n.Shared = m.Elem, m.Shared
+				m.Shared = nil
+				n.Right, _ = n.Right.deleteMin(fast, p)
 			} else {
-				n.Right, d = n.Right.delete(min, id, fast)
+				n.Right, d = n.Right.delete(min, id, fast, p)
 			}
 			if n.Right == nil {
 				n.Range.SetEnd(n.Elem.End())
@@ -430,12 +2461,110 @@ func (n *Node) delete(min Comparable, id uintptr, fast bool) (root *Node, d int)
 		}
 	}
 
-	root = n.fixUp(fast)
+	root = n.fixUp(fast, p)
 
 	return
 }
 
-// Return the left-most interval stored in the tree.
+// DeleteElem deletes a stored interval s that overlaps e according to e.Overlap() and
+// satisfies equal(s, e), returning whether such an interval was found. Only one interval is
+// deleted; it is the first satisfying interval in sort order. Other intervals overlapping e,
+// including those with the same start and end values, are not altered. If e is nil,
+// ErrNilOverlapper is returned.
+func (t *Tree) DeleteElem(e Interface, equal func(a, b Interface) bool, fast bool) (ok bool, err error) {
+	if err = t.writable(); err != nil {
+		return false, err
+	}
+	if e == nil {
+		return false, ErrNilOverlapper
+	}
+	if e.Start().Compare(e.End()) > 0 {
+		return false, ErrInvertedRange
+	}
+	var s Interface
+	t.DoMatching(func(o Interface) (done bool) {
+		if equal(o, e) {
+			s = o
+			return true
+		}
+		return
+	}, e)
+	if s == nil {
+		return false, nil
+	}
+	t.deleteStored(s, fast)
+	return true, nil
+}
+
+// deleteStored deletes the stored interval s, which may be a shared interval.
+func (t *Tree) deleteStored(s Interface, fast bool) {
+	var d int
+	t.Root, d = t.Root.delete(s.Start(), s.ID(), fast, t.env)
+	t.Count += d
+	if t.Root != nil {
+		t.Root.Color = llrb.Black
+	}
+}
+
+// A Handle identifies an interval inserted into a Tree by InsertHandle.
+type Handle struct {
+	e Interface
+}
+
+// Elem returns the interval identified by the Handle.
+func (h Handle) Elem() Interface { return h.e }
+
+// InsertHandle inserts the Interface e into the Tree in the same way as Insert, returning a
+// Handle that may be passed to DeleteHandle to delete precisely e.
+func (t *Tree) InsertHandle(e Interface, fast bool) (Handle, error) {
+	err := t.Insert(e, fast)
+	if err != nil {
+		return Handle{}, err
+	}
+	return Handle{e: e}, nil
+}
+
+// DeleteHandle deletes the interval identified by h, returning whether it was found. Only the
+// stored interval with the start value and ID of the interval identified by h is deleted, so
+// other stored intervals overlapping it, including shared intervals with the same start and
+// end values, are not altered.
+func (t *Tree) DeleteHandle(h Handle, fast bool) (ok bool, err error) {
+	if err = t.writable(); err != nil {
+		return false, err
+	}
+	if h.e == nil {
+		return false, ErrNilOverlapper
+	}
+	_, s := t.seekStored(h.e, func(a, b Interface) bool { return a.ID() == b.ID() })
+	if s == nil {
+		return false, nil
+	}
+	t.deleteStored(s, fast)
+	return true, nil
+}
+
+// DeleteAll deletes all intervals stored in the Tree that overlap q according to q.Overlap(),
+// returning the number of intervals deleted. Matching intervals are collected before any
+// deletion is made, so the set of deleted intervals is not altered by restructuring of the
+// Tree during deletion.
+func (t *Tree) DeleteAll(q Overlapper, fast bool) int {
+	t.mustBeWritable()
+	var n int
+	for _, e := range t.Get(q) {
+		var d int
+		t.Root, d = t.Root.delete(e.Start(), e.ID(), fast, t.env)
+		n -= d
+		if t.Root == nil {
+			break
+		}
+		t.Root.Color = llrb.Black
+	}
+	t.Count -= n
+	return n
+}
+
+// Return the left-most interval stored in the tree. This is the interval with the smallest
+// start value, not necessarily the interval with the smallest end value.
 func (t *Tree) Min() Interface {
 	if t.Root == nil {
 		return nil
@@ -449,7 +2578,8 @@ func (n *Node) min() *Node {
 	return n
 }
 
-// Return the right-most interval stored in the tree.
+// Return the right-most interval stored in the tree. This is the interval with the largest
+// start value, not necessarily the interval that extends furthest; see MaxEnd.
 func (t *Tree) Max() Interface {
 	if t.Root == nil {
 		return nil
@@ -463,16 +2593,405 @@ func (n *Node) max() *Node {
 	return n
 }
 
+// MaxEnd returns the interval stored in the tree with the largest end value. If more than one
+// interval shares the largest end value, the left-most of these is returned. MaxEnd relies on
+// the Tree's ranges, so AdjustRanges must be called before MaxEnd is used if fast insertion or
+// deletion has been performed.
+func (t *Tree) MaxEnd() Interface {
+	if t.Root == nil {
+		return nil
+	}
+	return t.Root.maxEnd()
+}
+
+// maxEnd returns the left-most interval with the largest end value in the subtree rooted at n.
+func (n *Node) maxEnd() Interface {
+	end := n.Range.End()
+	for {
+		switch {
+		case n.Left != nil && n.Left.Range.End().Compare(end) == 0:
+			n = n.Left
+		case n.Elem.End().Compare(end) == 0:
+			return n.Elem
+		default:
+			n = n.Right
+		}
+	}
+}
+
+// Nearest returns the interval stored in the Tree that is nearest to the point p, or nil if
+// the Tree is empty. The distance from p to an interval is zero if start <= p <= end, and
+// otherwise dist(p, start) for an interval starting after p or dist(end, p) for an interval
+// ending before p. The candidates considered are the interval with the largest end value
+// among those starting at or before p and the first interval starting after p; where these
+// are equally distant, the former is returned. Nearest relies on the Tree's ranges, so
+// AdjustRanges must be called before Nearest is used if fast insertion or deletion has been
+// performed.
+func (t *Tree) Nearest(p Comparable, dist func(a, b Comparable) float64) Interface {
+	var (
+		left  Interface
+		sub   *Node
+		end   Comparable
+		right *Node
+	)
+	for n := t.Root; n != nil; {
+		if p.Compare(n.Elem.Start()) < 0 {
+			right, n = n, n.Left
+			continue
+		}
+		if n.Left != nil && (end == nil || n.Left.Range.End().Compare(end) > 0) {
+			left, sub, end = nil, n.Left, n.Left.Range.End()
+		}
+		if end == nil || n.Elem.End().Compare(end) > 0 {
+			left, sub, end = n.Elem, nil, n.Elem.End()
+		}
+		n = n.Right
+	}
+	if sub != nil {
+		left = sub.maxEnd()
+	}
+	switch {
+	case left == nil && right == nil:
+		return nil
+	case right == nil:
+		return left
+	case left == nil:
+		return right.Elem
+	}
+	if p.Compare(end) <= 0 {
+		return left
+	}
+	if dist(p, right.Elem.Start()) < dist(end, p) {
+		return right.Elem
+	}
+	return left
+}
+
+// KNearest returns at most k intervals stored in the Tree in ascending order of their distance
+// from the point p as given by dist, with ties resolved in favor of intervals starting at or
+// before p. dist must be zero for intervals containing p and must not decrease as intervals
+// lie further from p; that is, for intervals starting at or before p it must not increase with
+// end value and for intervals starting after p it must not decrease with start value. The
+// Tree is searched outward from p in both directions and the search stops once k intervals
+// have been found, so dist is only called for O(k) intervals when the Tree's ranges allow
+// the search to be pruned. KNearest relies on the Tree's ranges, so AdjustRanges must be
+// called before KNearest is used if fast insertion or deletion has been performed.
+func (t *Tree) KNearest(p Comparable, k int, dist func(p Comparable, e Interface) float64) []Interface {
+	if k <= 0 || t.Root == nil {
+		return nil
+	}
+
+	// Intervals starting at or before p are visited in descending order of end value
+	// by a best-first search over subtrees keyed by their range end values.
+	before := nearHeap{{n: t.Root, key: t.Root.Range.End()}}
+	nextBefore := func() Interface {
+		for before.Len() != 0 {
+			it := heap.Pop(&before).(nearItem)
+			if it.e != nil {
+				return it.e
+			}
+			n := it.n
+			if !it.full && n.Elem.Start().Compare(p) > 0 {
+				if n.Left != nil {
+					heap.Push(&before, nearItem{n: n.Left, key: n.Left.Range.End()})
+				}
+				continue
+			}
+			if n.Left != nil {
+				heap.Push(&before, nearItem{n: n.Left, key: n.Left.Range.End(), full: true})
+			}
+			n.each(func(e Interface) (done bool) {
+				heap.Push(&before, nearItem{e: e, key: e.End()})
+				return
+			})
+			if n.Right != nil {
+				heap.Push(&before, nearItem{n: n.Right, key: n.Right.Range.End(), full: it.full})
+			}
+		}
+		return nil
+	}
+
+	// Intervals starting after p are visited in sort order.
+	after := &Cursor{t: t}
+	for n := t.Root; n != nil; {
+		if p.Compare(n.Elem.Start()) < 0 {
+			after.stack = append(after.stack, n)
+			n = n.Left
+		} else {
+			n = n.Right
+		}
+	}
+
+	var (
+		o      []Interface
+		b      = nextBefore()
+		a, _   = after.Next()
+		db, da float64
+	)
+	if b != nil {
+		db = dist(p, b)
+	}
+	if a != nil {
+		da = dist(p, a)
+	}
+	for len(o) < k && (b != nil || a != nil) {
+		if a == nil || (b != nil && db <= da) {
+			o = append(o, b)
+			if b = nextBefore(); b != nil {
+				db = dist(p, b)
+			}
+		} else {
+			o = append(o, a)
+			if a, _ = after.Next(); a != nil {
+				da = dist(p, a)
+			}
+		}
+	}
+	return o
+}
+
+// nearItem is a subtree or an interval in the best-first search of KNearest. Subtrees are
+// either full, holding only intervals starting at or before the query point, or partial.
+type nearItem struct {
+	n    *Node
+	e    Interface
+	full bool
+	key  Comparable
+}
+
+// nearHeap is a max-heap of nearItems ordered by key.
+type nearHeap []nearItem
+
+func (h nearHeap) Len() int            { return len(h) }
+func (h nearHeap) Less(i, j int) bool  { return h[i].key.Compare(h[j].key) > 0 }
+func (h nearHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nearHeap) Push(x interface{}) { *h = append(*h, x.(nearItem)) }
+func (h *nearHeap) Pop() interface{} {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+// TopKByEnd returns the k intervals stored in the Tree with the largest end values, in
+// descending order of end value. If fewer than k intervals are stored, all are returned.
+// Which of several intervals sharing the smallest returned end value are included is
+// unspecified. Subtrees that cannot hold a better interval than those already found are
+// pruned using their ranges, so AdjustRanges must be called before TopKByEnd is used if fast
+// insertion or deletion has been performed.
+func (t *Tree) TopKByEnd(k int) []Interface {
+	if k <= 0 || t.Root == nil {
+		return nil
+	}
+	h := make(endHeap, 0, k)
+	t.Root.topKByEnd(k, &h)
+	o := make([]Interface, len(h))
+	for i := len(o) - 1; i >= 0; i-- {
+		o[i] = heap.Pop(&h).(Interface)
+	}
+	return o
+}
+func (n *Node) topKByEnd(k int, h *endHeap) {
+	if n == nil || (len(*h) == k && n.Range.End().Compare((*h)[0].End()) <= 0) {
+		return
+	}
+	n.each(func(e Interface) (done bool) {
+		switch {
+		case len(*h) < k:
+			heap.Push(h, e)
+		case e.End().Compare((*h)[0].End()) > 0:
+			(*h)[0] = e
+			heap.Fix(h, 0)
+		}
+		return
+	})
+	first, second := n.Left, n.Right
+	if first == nil || (second != nil && second.Range.End().Compare(first.Range.End()) > 0) {
+		first, second = second, first
+	}
+	first.topKByEnd(k, h)
+	second.topKByEnd(k, h)
+}
+
+// endHeap is a min-heap of intervals ordered by end value.
+type endHeap []Interface
+
+func (h endHeap) Len() int            { return len(h) }
+func (h endHeap) Less(i, j int) bool  { return h[i].End().Compare(h[j].End()) < 0 }
+func (h endHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *endHeap) Push(x interface{}) { *h = append(*h, x.(Interface)) }
+func (h *endHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Select returns the interval at index k of the sort order of the Tree. If k is
+// outside the range [0, t.Len()), ErrOutOfRange is returned.
+func (t *Tree) Select(k int) (Interface, error) {
+	if k < 0 || k >= t.Count {
+		return nil, ErrOutOfRange
+	}
+	n, i := t.Root.selectNode(k)
+	if i == 0 {
+		return n.Elem, nil
+	}
+	return n.Shared[i-1], nil
+}
+
+// Partitions returns the intervals that divide the sort order of the Tree into n groups of
+// as nearly equal size as possible, in sort order. The i-th returned interval is the first of
+// the (i+1)-th group, so the start values of the returned intervals may be used as split keys
+// for sharding work by coordinate. At most n-1 intervals are returned, and fewer if the Tree
+// holds fewer than n intervals. Each interval is found in O(log n) time using subtree sizes.
+func (t *Tree) Partitions(n int) []Interface {
+	if n < 2 || t.Count < 2 {
+		return nil
+	}
+	if n > t.Count {
+		n = t.Count
+	}
+	o := make([]Interface, 0, n-1)
+	for i := 1; i < n; i++ {
+		e, _ := t.Select(i * t.Count / n)
+		o = append(o, e)
+	}
+	return o
+}
+
+// selectNode returns the node holding the interval at index k of the subtree rooted at n,
+// and the index of the interval within the node, with zero indicating the Elem and i > 0
+// indicating Shared[i-1].
+func (n *Node) selectNode(k int) (*Node, int) {
+	for {
+		l := n.Left.size()
+		switch {
+		case k < l:
+			n = n.Left
+		case k <= l+len(n.Shared):
+			return n, k - l
+		default:
+			k -= l + 1 + len(n.Shared)
+			n = n.Right
+		}
+	}
+}
+
+// Rank returns the number of intervals stored in the Tree that sort before q according
+// to q.Start().Compare(), with ties broken by comparison of ID() values. If q is nil, Rank
+// returns zero.
+func (t *Tree) Rank(q Interface) int {
+	if q == nil {
+		return 0
+	}
+	var (
+		r  int
+		m  = q.Start()
+		id = q.ID()
+	)
+	for n := t.Root; n != nil; {
+		c := m.Compare(n.Elem.Start())
+		switch {
+		case c < 0 || (c == 0 && id < n.Elem.ID()):
+			n = n.Left
+		case c == 0 && id == n.Elem.ID():
+			return r + n.Left.size()
+		default:
+			r += n.Left.size() + 1
+			if i := n.sharedIndex(m, id); i < len(n.Shared) {
+				return r + i
+			}
+			r += len(n.Shared)
+			n = n.Right
+		}
+	}
+	return r
+}
+
+// RangeCount returns the number of intervals stored in the Tree with a start value within the
+// half-open range [lo, hi), according to Compare. RangeCount takes O(log n) time. If lo is not
+// less than hi, zero is returned.
+func (t *Tree) RangeCount(lo, hi Comparable) int {
+	if lo.Compare(hi) >= 0 {
+		return 0
+	}
+	return t.Root.countBelow(hi) - t.Root.countBelow(lo)
+}
+
+// countBelow returns the number of intervals stored in the subtree rooted at n with a start
+// value less than p.
+func (n *Node) countBelow(p Comparable) int {
+	var c int
+	for n != nil {
+		if p.Compare(n.Elem.Start()) <= 0 {
+			n = n.Left
+		} else {
+			c += n.Left.size() + 1 + len(n.Shared)
+			n = n.Right
+		}
+	}
+	return c
+}
+
+// estimateDepth is the depth below which EstimateOverlaps stops descending the Tree.
+const estimateDepth = 8
+
+// EstimateOverlaps returns an approximate count of the intervals stored in the Tree that
+// overlap q according to q.Overlap(). The Tree is descended only to a fixed depth, so at most
+// a few hundred nodes are visited however many intervals overlap q. A subtree whose Range
+// lies within q, when q is also a Range, is counted in full from its Size, and a subtree at
+// the depth limit whose Range overlaps q is assumed to have half of its intervals overlap q.
+// The estimate is exact for shallow trees and when q covers whole subtrees, but it is otherwise
+// only suitable for planning. Ranges must be current, so AdjustRanges must have been called
+// if fast insertion or deletion has been performed. If q is nil, zero is returned.
+func (t *Tree) EstimateOverlaps(q Overlapper) int {
+	if q == nil {
+		return 0
+	}
+	r, _ := q.(Range)
+	return t.Root.estimate(q, r, estimateDepth)
+}
+
+func (n *Node) estimate(q Overlapper, r Range, d int) int {
+	if n == nil || !q.Overlap(n.Range) {
+		return 0
+	}
+	if r != nil && r.Start().Compare(n.Range.Start()) <= 0 && n.Range.End().Compare(r.End()) <= 0 {
+		return n.Size
+	}
+	if d == 0 {
+		return (n.Size + 1) / 2
+	}
+	c := n.Left.estimate(q, r, d-1) + n.Right.estimate(q, r, d-1)
+	if q.Overlap(n.Elem) {
+		c += 1 + len(n.Shared)
+	}
+	return c
+}
+
 // Floor returns the largest value equal to or less than the query q according to
-// q.Start().Compare(), with ties broken by comparison of ID() values.
+// q.Start().Compare(), with ties broken by comparison of ID() values. If q is nil,
+// ErrNilOverlapper is returned.
 func (t *Tree) Floor(q Interface) (o Interface, err error) {
+	if q == nil {
+		return nil, ErrNilOverlapper
+	}
 	if t.Root == nil {
 		return
 	}
-	n := t.Root.floor(q.Start(), q.ID())
+	m, id := q.Start(), q.ID()
+	n := t.Root.floor(m, id)
 	if n == nil {
 		return
 	}
+	i := n.sharedIndex(m, id)
+	if i < len(n.Shared) && compare(m, id, n.Shared[i]) == 0 {
+		i++
+	}
+	if i > 0 {
+		return n.Shared[i-1], nil
+	}
 	return n.Elem, nil
 }
 
@@ -482,10 +3001,10 @@ func (n *Node) floor(m Comparable, id uintptr) *Node {
 	}
 	switch c := m.Compare(n.Elem.Start()); {
 	case c == 0:
-		switch cid := id - n.Elem.ID(); {
-		case cid == 0:
+		switch eid := n.Elem.ID(); {
+		case id == eid:
 			return n
-		case cid < 0:
+		case id < eid:
 			return n.Left.floor(m, id)
 		default:
 			if r := n.Right.floor(m, id); r != nil {
@@ -503,12 +3022,22 @@ func (n *Node) floor(m Comparable, id uintptr) *Node {
 }
 
 // Ceil returns the smallest value equal to or greater than the query q according to
-// q.Start().Compare(), with ties broken by comparison of ID() values.
+// q.Start().Compare(), with ties broken by comparison of ID() values. If q is nil,
+// ErrNilOverlapper is returned.
 func (t *Tree) Ceil(q Interface) (o Interface, err error) {
+	if q == nil {
+		return nil, ErrNilOverlapper
+	}
 	if t.Root == nil {
 		return
 	}
-	n := t.Root.ceil(q.Start(), q.ID())
+	m, id := q.Start(), q.ID()
+	if f := t.Root.floor(m, id); f != nil && compare(m, id, f.Elem) != 0 {
+		if i := f.sharedIndex(m, id); i < len(f.Shared) {
+			return f.Shared[i], nil
+		}
+	}
+	n := t.Root.ceil(m, id)
 	if n == nil {
 		return
 	}
@@ -521,10 +3050,10 @@ func (n *Node) ceil(m Comparable, id uintptr) *Node {
 	}
 	switch c := m.Compare(n.Elem.Start()); {
 	case c == 0:
-		switch cid := id - n.Elem.ID(); {
-		case cid == 0:
+		switch eid := n.Elem.ID(); {
+		case id == eid:
 			return n
-		case cid > 0:
+		case id > eid:
 			return n.Right.ceil(m, id)
 		default:
 			if l := n.Left.ceil(m, id); l != nil {
@@ -541,11 +3070,158 @@ func (n *Node) ceil(m Comparable, id uintptr) *Node {
 	return n
 }
 
+// FloorWhere returns the largest interval equal to or less than the query q, in the order
+// used by Floor, for which pred returns true. Intervals failing pred are passed over by
+// continuing the traversal in reverse sort order from the position of the floor of q, so the
+// cost is O(log n) plus the number of intervals passed over. Shared intervals are tested in
+// their traversal order. If q is nil, ErrNilOverlapper is returned.
+func (t *Tree) FloorWhere(q Interface, pred func(Interface) bool) (o Interface, err error) {
+	if q == nil {
+		return nil, ErrNilOverlapper
+	}
+	c := t.ReverseCursor()
+	c.SeekReverse(q)
+	for e, ok := c.Prev(); ok; e, ok = c.Prev() {
+		if pred(e) {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// CeilWhere returns the smallest interval equal to or greater than the query q, in the order
+// used by Ceil, for which pred returns true. Intervals failing pred are passed over by
+// continuing the traversal in sort order from the position of the ceiling of q, so the cost
+// is O(log n) plus the number of intervals passed over. Shared intervals are tested in their
+// traversal order. If q is nil, ErrNilOverlapper is returned.
+func (t *Tree) CeilWhere(q Interface, pred func(Interface) bool) (o Interface, err error) {
+	if q == nil {
+		return nil, ErrNilOverlapper
+	}
+	c := t.Cursor()
+	c.Seek(q)
+	for e, ok := c.Next(); ok; e, ok = c.Next() {
+		if pred(e) {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// FloorPoint returns the largest interval with a start value equal to or less than p
+// according to p.Compare(). Only start values are considered, so a point equal to the start
+// value of an interval is its own floor, while a point equal to an end value is not treated
+// specially. Where several intervals share the floor start value, the last in sort order
+// is returned.
+func (t *Tree) FloorPoint(p Comparable) (o Interface, err error) {
+	var f *Node
+	for n := t.Root; n != nil; {
+		if p.Compare(n.Elem.Start()) < 0 {
+			n = n.Left
+		} else {
+			f, n = n, n.Right
+		}
+	}
+	if f == nil {
+		return
+	}
+	if k := len(f.Shared); k != 0 {
+		return f.Shared[k-1], nil
+	}
+	return f.Elem, nil
+}
+
+// CeilPoint returns the smallest interval with a start value equal to or greater than p
+// according to p.Compare(). Only start values are considered, so a point equal to the start
+// value of an interval is its own ceiling, while a point equal to an end value is not treated
+// specially. Where several intervals share the ceiling start value, the first in sort order
+// is returned.
+func (t *Tree) CeilPoint(p Comparable) (o Interface, err error) {
+	var f *Node
+	for n := t.Root; n != nil; {
+		if p.Compare(n.Elem.Start()) > 0 {
+			n = n.Right
+		} else {
+			f, n = n, n.Left
+		}
+	}
+	if f == nil {
+		return
+	}
+	return f.Elem, nil
+}
+
+// Predecessor returns the largest interval that is strictly less than the query q according
+// to q.Start().Compare(), with ties broken by comparison of ID() values. If no such interval
+// is stored in the Tree, nil is returned. If q is nil, ErrNilOverlapper is returned.
+func (t *Tree) Predecessor(q Interface) (o Interface, err error) {
+	if q == nil {
+		return nil, ErrNilOverlapper
+	}
+	var (
+		p     *Node
+		m, id = q.Start(), q.ID()
+	)
+	for n := t.Root; n != nil; {
+		if compare(m, id, n.Elem) > 0 {
+			p, n = n, n.Right
+		} else {
+			n = n.Left
+		}
+	}
+	if p == nil {
+		return
+	}
+	if i := p.sharedIndex(m, id); i > 0 {
+		return p.Shared[i-1], nil
+	}
+	return p.Elem, nil
+}
+
+// Successor returns the smallest interval that is strictly greater than the query q according
+// to q.Start().Compare(), with ties broken by comparison of ID() values. If no such interval
+// is stored in the Tree, nil is returned. If q is nil, ErrNilOverlapper is returned.
+func (t *Tree) Successor(q Interface) (o Interface, err error) {
+	if q == nil {
+		return nil, ErrNilOverlapper
+	}
+	var (
+		f, s  *Node
+		m, id = q.Start(), q.ID()
+	)
+	for n := t.Root; n != nil; {
+		if compare(m, id, n.Elem) < 0 {
+			s, n = n, n.Left
+		} else {
+			f, n = n, n.Right
+		}
+	}
+	if f != nil {
+		i := f.sharedIndex(m, id)
+		if i < len(f.Shared) && compare(m, id, f.Shared[i]) == 0 {
+			i++
+		}
+		if i < len(f.Shared) {
+			return f.Shared[i], nil
+		}
+	}
+	if s == nil {
+		return
+	}
+	return s.Elem, nil
+}
+
 // An Operation is a function that operates on an Interface. If done is returned true, the
 // Operation is indicating that no further work needs to be done and so the Do function should
 // traverse no further.
 type Operation func(Interface) (done bool)
 
+// A MetaOperation is a function that operates on an Interface stored in a Node at the given
+// depth below the root of a Tree and with the given color. If done is returned true, the
+// MetaOperation is indicating that no further work needs to be done and so the Do function
+// should traverse no further.
+type MetaOperation func(e Interface, depth int, color llrb.Color) (done bool)
+
 // Do performs fn on all intervals stored in the tree. A boolean is returned indicating whether the
 // Do traversal was interrupted by an Operation returning true. If fn alters stored intervals' sort
 // relationships, future tree operation behaviors are undefined.
@@ -563,7 +3239,7 @@ func (n *Node) do(fn Operation) (done bool) {
 			return
 		}
 	}
-	done = fn(n.Elem)
+	done = n.each(fn)
 	if done {
 		return
 	}
@@ -573,6 +3249,109 @@ func (n *Node) do(fn Operation) (done bool) {
 	return
 }
 
+// DoWithMeta performs fn on all intervals stored in the tree in sort order, passing the depth
+// and color of the Node holding each interval. The root has depth zero. A boolean is returned
+// indicating whether the traversal was interrupted by a MetaOperation returning true. If fn
+// alters stored intervals' sort relationships, future tree operation behaviors are undefined.
+func (t *Tree) DoWithMeta(fn MetaOperation) bool {
+	if t.Root == nil {
+		return false
+	}
+	return t.Root.doWithMeta(fn, 0)
+}
+
+func (n *Node) doWithMeta(fn MetaOperation, depth int) (done bool) {
+	if n.Left != nil {
+		done = n.Left.doWithMeta(fn, depth+1)
+		if done {
+			return
+		}
+	}
+	done = n.each(func(e Interface) bool { return fn(e, depth, n.Color) })
+	if done {
+		return
+	}
+	if n.Right != nil {
+		done = n.Right.doWithMeta(fn, depth+1)
+	}
+	return
+}
+
+// contextCheckInterval is the number of intervals visited by DoContext between checks
+// of the context.
+const contextCheckInterval = 64
+
+// DoContext performs fn on all intervals stored in the tree in sort order, checking ctx
+// periodically during the traversal. A boolean is returned indicating whether the traversal
+// was interrupted, either by an Operation returning true or by cancellation of ctx. If ctx
+// is cancelled, ctx.Err() is returned. If fn alters stored intervals' sort relationships,
+// future tree operation behaviors are undefined.
+func (t *Tree) DoContext(ctx context.Context, fn Operation) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return true, err
+	}
+	var (
+		n   int
+		err error
+	)
+	done := t.Do(func(e Interface) (done bool) {
+		n++
+		if n%contextCheckInterval == 0 {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				return true
+			default:
+			}
+		}
+		return fn(e)
+	})
+	return done, err
+}
+
+// DoChunked performs fn on the intervals stored in the Tree in sort order, passing them in
+// batches of n intervals, with a final smaller batch holding any remaining intervals. The
+// batch slice is reused between calls, so fn must not retain it. A boolean is returned
+// indicating whether the traversal was interrupted by fn returning true. If n is less than
+// one, ErrInvalidChunkSize is returned and fn is not called.
+func (t *Tree) DoChunked(n int, fn func(batch []Interface) (done bool)) (bool, error) {
+	if n < 1 {
+		return false, ErrInvalidChunkSize
+	}
+	size := n
+	if t.Count < size {
+		size = t.Count
+	}
+	batch := make([]Interface, 0, size)
+	if t.Do(func(e Interface) (done bool) {
+		batch = append(batch, e)
+		if len(batch) < n {
+			return
+		}
+		done = fn(batch)
+		batch = batch[:0]
+		return
+	}) {
+		return true, nil
+	}
+	if len(batch) != 0 {
+		return fn(batch), nil
+	}
+	return false, nil
+}
+
+// DoErr performs fn on all intervals stored in the tree in sort order, halting the traversal
+// at the first interval for which fn returns a non-nil error and returning that error. If fn
+// alters stored intervals' sort relationships, future tree operation behaviors are undefined.
+func (t *Tree) DoErr(fn func(Interface) error) error {
+	var err error
+	t.Do(func(e Interface) (done bool) {
+		err = fn(e)
+		return err != nil
+	})
+	return err
+}
+
 // DoReverse performs fn on all intervals stored in the tree, but in reverse of sort order. A boolean
 // is returned indicating whether the Do traversal was interrupted by an Operation returning true.
 // If fn alters stored intervals' sort relationships, future tree operation behaviors are undefined.
@@ -590,7 +3369,7 @@ func (n *Node) doReverse(fn Operation) (done bool) {
 			return
 		}
 	}
-	done = fn(n.Elem)
+	done = n.eachReverse(fn)
 	if done {
 		return
 	}
@@ -600,13 +3379,72 @@ func (n *Node) doReverse(fn Operation) (done bool) {
 	return
 }
 
+// DoRange performs fn on all intervals stored in the tree with start values over the interval
+// [from, to) from left to right. Only start values are considered, so intervals starting before
+// from are not visited even if they extend into [from, to). If to is less than from,
+// ErrInvertedRange is returned and fn is not called. A boolean is returned indicating whether
+// the Do traversal was interrupted by an Operation returning true. If fn alters stored
+// intervals' sort relationships, future tree operation behaviors are undefined.
+//
+// DoRange follows the name, argument order and half-open bounds of llrb.Tree.DoRange. Since a
+// Comparable has no successor value with which to express an inclusive upper bound as a
+// half-open one, DoRangeInclusive is provided for scans over a closed range.
+func (t *Tree) DoRange(fn Operation, from, to Comparable) (bool, error) {
+	return t.doRangeBounded(fn, from, to, false)
+}
+
+// DoRangeInclusive performs fn on all intervals stored in the tree with start values over the
+// closed interval [lo, hi] from left to right. It otherwise behaves as DoRange.
+func (t *Tree) DoRangeInclusive(fn Operation, lo, hi Comparable) (bool, error) {
+	return t.doRangeBounded(fn, lo, hi, true)
+}
+
+func (t *Tree) doRangeBounded(fn Operation, lo, hi Comparable, closed bool) (bool, error) {
+	if lo.Compare(hi) > 0 {
+		return false, ErrInvertedRange
+	}
+	if t.Root == nil {
+		return false, nil
+	}
+	return t.Root.doRange(fn, lo, hi, closed), nil
+}
+
+func (n *Node) doRange(fn Operation, lo, hi Comparable, closed bool) (done bool) {
+	start := n.Elem.Start()
+	lc, hc := lo.Compare(start), hi.Compare(start)
+	if lc <= 0 && n.Left != nil {
+		done = n.Left.doRange(fn, lo, hi, closed)
+		if done {
+			return
+		}
+	}
+	right := hc > 0 || (closed && hc == 0)
+	if lc <= 0 && right {
+		done = n.each(fn)
+		if done {
+			return
+		}
+	}
+	if right && n.Right != nil {
+		done = n.Right.doRange(fn, lo, hi, closed)
+	}
+	return
+}
+
 // DoMatch performs fn on all intervals stored in the tree that match q according to Overlap, with
 // q.Overlap() used to guide tree traversal, so DoMatching() will out perform Do() with a called
 // conditional function if the condition is based on sort order, but can not be reliably used if
 // the condition is independent of sort order. A boolean is returned indicating whether the Do
 // traversal was interrupted by an Operation returning true. If fn alters stored intervals' sort
-// relationships, future tree operation behaviors are undefined.
+// relationships, future tree operation behaviors are undefined. If q is nil, no traversal is
+// made and false is returned.
 func (t *Tree) DoMatching(fn Operation, q Overlapper) bool {
+	if q == nil {
+		return false
+	}
+	if debug {
+		t.checkDepth()
+	}
 	if t.Root != nil && q.Overlap(t.Root.Range) {
 		return t.Root.doMatch(fn, q)
 	}
@@ -621,7 +3459,7 @@ func (n *Node) doMatch(fn Operation, q Overlapper) (done bool) {
 		}
 	}
 	if q.Overlap(n.Elem) {
-		done = fn(n.Elem)
+		done = n.each(fn)
 		if done {
 			return
 		}
@@ -632,15 +3470,57 @@ func (n *Node) doMatch(fn Operation, q Overlapper) (done bool) {
 	return
 }
 
+// DoMatchingStats performs fn on all intervals stored in the tree that match q according to
+// Overlap, in the same way as DoMatching, and reports the number of nodes visited and the number
+// of intervals passed to fn during the traversal. Comparing visited with the number of stored
+// intervals indicates how effectively the tree's ranges prune the query. The returned boolean
+// indicates whether the traversal was interrupted by an Operation returning true. If q is nil,
+// ErrNilOverlapper is returned, and if q implements Range and has a start value greater than
+// its end value, ErrInvertedRange is returned; in both cases fn is not called.
+func (t *Tree) DoMatchingStats(fn Operation, q Overlapper) (visited, matched int, interrupted bool, err error) {
+	if err = checkQuery(q); err != nil {
+		return 0, 0, false, err
+	}
+	if t.Root == nil || !q.Overlap(t.Root.Range) {
+		return 0, 0, false, nil
+	}
+	interrupted = t.Root.doMatchStats(func(e Interface) (done bool) {
+		matched++
+		return fn(e)
+	}, q, &visited)
+	return visited, matched, interrupted, nil
+}
+
+func (n *Node) doMatchStats(fn Operation, q Overlapper, visited *int) (done bool) {
+	*visited++
+	if n.Left != nil && q.Overlap(n.Left.Range) {
+		done = n.Left.doMatchStats(fn, q, visited)
+		if done {
+			return
+		}
+	}
+	if q.Overlap(n.Elem) {
+		done = n.each(fn)
+		if done {
+			return
+		}
+	}
+	if n.Right != nil && q.Overlap(n.Right.Range) {
+		done = n.Right.doMatchStats(fn, q, visited)
+	}
+	return
+}
+
 // DoMatchReverse performs fn on all intervals stored in the tree that match q according to Overlap,
 // with q.Overlap() used to guide tree traversal, so DoMatching() will out perform Do() with a called
 // conditional function if the condition is based on sort order, but can not be reliably used if
 // the condition is independent of sort order. A boolean is returned indicating whether the Do
 // traversal was interrupted by an Operation returning true. If fn alters stored intervals' sort
-// relationships, future tree operation behaviors are undefined.
+// relationships, future tree operation behaviors are undefined. If q is nil, no traversal is
+// made and false is returned.
 func (t *Tree) DoMatchingReverse(fn Operation, q Overlapper) bool {
-	if t.Root != nil && q.Overlap(t.Root.Range) {
-		return t.Root.doMatch(fn, q)
+	if q != nil && t.Root != nil && q.Overlap(t.Root.Range) {
+		return t.Root.doMatchReverse(fn, q)
 	}
 	return false
 }
@@ -653,7 +3533,7 @@ func (n *Node) doMatchReverse(fn Operation, q Overlapper) (done bool) {
 		}
 	}
 	if q.Overlap(n.Elem) {
-		done = fn(n.Elem)
+		done = n.eachReverse(fn)
 		if done {
 			return
 		}
@@ -6,11 +6,15 @@ package interval
 
 import (
 	"code.google.com/p/biogo.store/llrb"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	check "launchpad.net/gocheck"
+	"math"
 	"math/rand"
 	"os"
+	"sort"
 	"strings"
 	"testing"
 	"unsafe"
@@ -140,6 +144,22 @@ func (n *Node) bounding(m Mutable) Mutable {
 	return m
 }
 
+// Does every node correctly annotate the size of its subtree.
+func (t *Tree) isSized() bool {
+	if t == nil {
+		return true
+	}
+	return t.Root.size() == t.Count && t.Root.isSized()
+}
+func (n *Node) isSized() bool {
+	if n == nil {
+		return true
+	}
+	return n.Size == n.Left.size()+n.Right.size()+1 &&
+		n.Left.isSized() &&
+		n.Right.isSized()
+}
+
 // Test helpers
 
 type overRune rune
@@ -169,8 +189,10 @@ type overlap struct {
 }
 
 func (o *overlap) Overlap(b Range) bool {
-	bc := b.(*overlap)
-	return o.end > bc.start && o.start < bc.end
+	if bc, ok := b.(*overlap); ok {
+		return o.end > bc.start && o.start < bc.end
+	}
+	return o.end > b.Start().(compInt) && o.start < b.End().(compInt)
 }
 func (o *overlap) ID() uintptr           { return o.id }
 func (o *overlap) Start() Comparable     { return o.start }
@@ -220,10 +242,20 @@ func makeTree(desc string) (n *Node) {
 		panic("cannot reach")
 	}
 
+	var size func(*Node) int
+	size = func(n *Node) int {
+		if n == nil {
+			return 0
+		}
+		n.Size = size(n.Left) + size(n.Right) + 1
+		return n.Size
+	}
+
 	n, _ = build([]rune(desc))
 	if n.Left == nil && n.Right == nil {
 		n = nil
 	}
+	size(n)
 
 	return
 }
@@ -315,7 +347,7 @@ func (s *S) TestRotateLeft(c *check.C) {
 
 	tree := makeTree(orig)
 
-	tree = tree.rotateLeft()
+	tree = tree.rotateLeft(nil)
 	c.Check(tree.describeTree(true, false), check.Equals, rot)
 
 	rotTree := makeTree(rot)
@@ -329,7 +361,7 @@ func (s *S) TestRotateRight(c *check.C) {
 
 	tree := makeTree(orig)
 
-	tree = tree.rotateRight()
+	tree = tree.rotateRight(nil)
 	c.Check(tree.describeTree(true, false), check.Equals, rot)
 
 	rotTree := makeTree(rot)
@@ -349,6 +381,193 @@ func (s *S) TestNilOperations(c *check.C) {
 	c.Check(*t, check.Equals, Tree{})
 }
 
+func (s *S) TestValidate(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	c.Check(t.Validate(), check.Equals, nil)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+		c.Assert(t.Validate(), check.Equals, nil)
+	}
+
+	n := t.Root.Left
+	n.Range.SetEnd(n.Range.End().(compInt) + 1)
+	c.Check(t.Validate(), check.ErrorMatches, "interval: invalid tree: incorrect range at .*")
+	n.Range.SetEnd(n.Range.End().(compInt) - 1)
+	c.Check(t.Validate(), check.Equals, nil)
+
+	n.Size++
+	c.Check(t.Validate(), check.ErrorMatches, "interval: invalid tree: incorrect size at .*")
+	n.Size--
+
+	t.Count++
+	c.Check(t.Validate(), check.ErrorMatches, "interval: invalid tree: count .* does not match size .*")
+	t.Count--
+
+	n.Left.Color = !n.Left.Color
+	c.Check(t.Validate(), check.NotNil)
+	n.Left.Color = !n.Left.Color
+
+	e := n.Elem
+	n.Elem = &overlap{start: compInt(max) + length, end: compInt(max) + 2*length, id: e.ID()}
+	c.Check(t.Validate(), check.ErrorMatches, "interval: invalid tree: .* out of sort order")
+	n.Elem = e
+	c.Check(t.Validate(), check.Equals, nil)
+
+	var ranged func(*Node)
+	ranged = func(n *Node) {
+		if n == nil {
+			return
+		}
+		s := compInt(n.Elem.(overRune))
+		n.Elem = &overlap{start: s, end: s + 1}
+		n.Range = n.Elem.NewMutable()
+		ranged(n.Left)
+		ranged(n.Right)
+	}
+	u := makeTree("((a,c)b,(e,g)f)d;")
+	ranged(u)
+	(&Tree{Root: u}).AdjustRanges()
+	u.Color = llrb.Black
+	u.Left.Color = llrb.Black
+	u.Left.Left.Color = llrb.Black
+	u.Left.Right.Color = llrb.Black
+	u.Right.Left.Color = llrb.Black
+	u.Right.Right.Color = llrb.Black
+	t = &Tree{Root: u, Count: 7}
+	c.Check(t.Validate(), check.ErrorMatches, "interval: invalid tree: right-leaning red link at .*")
+	u.Right.Color = llrb.Black
+	u.Left.Color = llrb.Red
+	u.Left.Left.Color = llrb.Red
+	c.Check(t.Validate(), check.ErrorMatches, "interval: invalid tree: consecutive red links at .*")
+	u.Left.Left.Color = llrb.Black
+	c.Check(t.Validate(), check.ErrorMatches, "interval: invalid tree: unbalanced black height at .*")
+	u.Left.Color = llrb.Black
+	c.Check(t.Validate(), check.Equals, nil)
+}
+
+func (s *S) TestCoverage(c *check.C) {
+	var (
+		t      = &Tree{}
+		length = func(start, end Comparable) int { return int(end.(compInt) - start.(compInt)) }
+	)
+	c.Check(t.Coverage(length), check.Equals, 0)
+	for i, iv := range []*overlap{
+		{start: 0, end: 2},
+		{start: 2, end: 4},
+		{start: 1, end: 6},
+		{start: 3, end: 4},
+		{start: 7, end: 7},
+		{start: 9, end: 12},
+		{start: 10, end: 11},
+		{start: 11, end: 15},
+		{start: 20, end: 21},
+	} {
+		iv.id = uintptr(i)
+		t.Insert(iv, false)
+	}
+	c.Check(t.Coverage(length), check.Equals, 6+0+6+1)
+
+	var (
+		count, max = 1000, 1000
+		covered    = make([]bool, max+10)
+	)
+	t = &Tree{}
+	for i := 0; i < count; i++ {
+		s := rand.Intn(max)
+		e := s + rand.Intn(10)
+		t.Insert(&overlap{start: compInt(s), end: compInt(e), id: uintptr(i)}, false)
+		for j := s; j < e; j++ {
+			covered[j] = true
+		}
+	}
+	var want int
+	for _, b := range covered {
+		if b {
+			want++
+		}
+	}
+	c.Check(t.Coverage(length), check.Equals, want)
+}
+
+func (s *S) TestClear(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 100; i++ {
+		t.Insert(&overlap{start: i, end: i + 1, id: uintptr(i)}, false)
+	}
+	t.Clear()
+	c.Check(*t, check.Equals, Tree{})
+	t.Insert(&overlap{start: 0, end: 1}, false)
+	c.Check(t.Len(), check.Equals, 1)
+	c.Check(t.Get(&overlap{start: 0, end: 1}), check.HasLen, 1)
+}
+
+func (s *S) TestHeight(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	c.Check(t.Height(), check.Equals, 0)
+	c.Check(t.BlackHeight(), check.Equals, 0)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+		c.Check(float64(t.Height()) <= 2*math.Log2(float64(t.Len()+1)), check.Equals, true,
+			check.Commentf("height %d for %d elements", t.Height(), t.Len()))
+		c.Check(t.BlackHeight() > 0, check.Equals, true)
+	}
+
+	u := makeTree("((a,c)b,(e,g)f)d;")
+	u.Color = llrb.Black
+	u.Left.Color = llrb.Black
+	t = &Tree{Root: u}
+	c.Check(t.Height(), check.Equals, 3)
+	c.Check(t.BlackHeight(), check.Equals, -1)
+	u.Right.Color = llrb.Black
+	c.Check(t.BlackHeight(), check.Equals, 2)
+}
+
+func (s *S) TestNewFromSorted(c *check.C) {
+	for n := 0; n <= 300; n++ {
+		var elems []Interface
+		for i := 0; i < n; i++ {
+			s := compInt(i / 3)
+			elems = append(elems, &overlap{start: s, end: s + compInt(rand.Intn(10)) + 1, id: uintptr(i)})
+		}
+		t, err := NewFromSorted(elems)
+		c.Assert(err, check.Equals, nil)
+		c.Check(t.Len(), check.Equals, n)
+		c.Assert(t.Validate(), check.Equals, nil, check.Commentf("n=%d", n))
+		c.Check(t.isBalanced(), check.Equals, true)
+		c.Check(t.is23_234(), check.Equals, true)
+		c.Check(t.isRanged(), check.Equals, true)
+		c.Check(float64(t.Height()) <= 2*math.Log2(float64(n+1)), check.Equals, true)
+		var got []Interface
+		t.Do(func(e Interface) (done bool) { got = append(got, e); return })
+		c.Check(got, check.DeepEquals, elems)
+
+		for i := 0; i < n; i++ {
+			if i%2 == 0 {
+				t.Delete(elems[i], false)
+			}
+		}
+		c.Check(t.Len(), check.Equals, n/2)
+		c.Assert(t.Validate(), check.Equals, nil)
+	}
+
+	_, err := NewFromSorted([]Interface{&overlap{start: 1, end: 2}, &overlap{start: 0, end: 2}})
+	c.Check(err, check.Equals, ErrUnsorted)
+	_, err = NewFromSorted([]Interface{&overlap{start: 1, end: 2}, &overlap{start: 1, end: 2}})
+	c.Check(err, check.Equals, ErrUnsorted)
+	_, err = NewFromSorted([]Interface{&overlap{start: 1, end: 0}})
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
 func (s *S) TestRange(c *check.C) {
 	t := &Tree{}
 	for i, iv := range []*overlap{
@@ -387,6 +606,7 @@ func (s *S) TestInsertion(c *check.C) {
 		failed = failed || !c.Check(t.is23_234(), check.Equals, true)
 		failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
 		failed = failed || !c.Check(t.isRanged(), check.Equals, true)
+		failed = failed || !c.Check(t.isSized(), check.Equals, true)
 		if failed {
 			if *printTree {
 				c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
@@ -449,6 +669,7 @@ func (s *S) TestDeletion(c *check.C) {
 			failed = failed || !c.Check(t.is23_234(), check.Equals, true)
 			failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
 			failed = failed || !c.Check(t.isRanged(), check.Equals, true)
+			failed = failed || !c.Check(t.isSized(), check.Equals, true)
 			if failed {
 				if *printTree {
 					c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
@@ -515,179 +736,2348 @@ func (s *S) TestGet(c *check.C) {
 	}
 }
 
-func (s *S) TestFloor(c *check.C) {
-	min, max := compInt(0), compInt(1000)
+func (s *S) TestInsertOrReplace(c *check.C) {
+	var (
+		t     = &Tree{}
+		equal = func(a, b Interface) bool {
+			ao, bo := a.(*overlap), b.(*overlap)
+			return ao.start == bo.start && ao.end == bo.end
+		}
+	)
+	for i := compInt(0); i < 100; i++ {
+		r, err := t.InsertOrReplace(&overlap{start: i, end: i + 5, id: uintptr(i)}, equal, false)
+		c.Check(r, check.Equals, false)
+		c.Check(err, check.Equals, nil)
+	}
+	for i := compInt(0); i < 100; i++ {
+		e := &overlap{start: i, end: i + 5, id: uintptr(i)}
+		r, err := t.InsertOrReplace(e, equal, false)
+		c.Check(r, check.Equals, true)
+		c.Check(err, check.Equals, nil)
+		var found bool
+		for _, o := range t.Get(e) {
+			if o == Interface(e) {
+				found = true
+			}
+		}
+		c.Check(found, check.Equals, true)
+
+		// Replace with an interval that has a different ID.
+		e = &overlap{start: i, end: i + 5, id: uintptr(i + 1000)}
+		r, err = t.InsertOrReplace(e, equal, false)
+		c.Check(r, check.Equals, true)
+		c.Check(err, check.Equals, nil)
+	}
+	c.Check(t.Len(), check.Equals, 100)
+	c.Check(t.Validate(), check.Equals, nil)
+	t.Do(func(e Interface) (done bool) {
+		c.Check(e.ID() >= 1000, check.Equals, true)
+		return
+	})
+
+	r, err := t.InsertOrReplace(&overlap{start: 1, end: 0}, equal, false)
+	c.Check(r, check.Equals, false)
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestFindExact(c *check.C) {
 	t := &Tree{}
-	for i := min; i <= max; i++ {
-		if i&1 == 0 { // Insert even numbers only.
-			t.Insert(&overlap{start: i, end: i + 1}, false)
+	o, ok := t.FindExact(compInt(0), compInt(1))
+	c.Check(o, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+
+	var id uintptr
+	for i := compInt(0); i < 50; i++ {
+		for l := compInt(1); l <= 5; l++ {
+			t.Insert(&overlap{start: i, end: i + l, id: id}, false)
+			id++
 		}
 	}
-	for i := min; i <= max; i++ {
-		l, _ := t.Floor(&overlap{start: i, end: i + 1})
-		if i&1 == 0 {
-			c.Check(l, check.DeepEquals, &overlap{start: i, end: i + 1}) // Check even Floors are themselves.
-		} else {
-			c.Check(l, check.DeepEquals, &overlap{start: i - 1, end: i}) // Check odd Floors are the previous number.
+	for i := compInt(0); i < 50; i++ {
+		for l := compInt(1); l <= 5; l++ {
+			o, ok := t.FindExact(i, i+l)
+			c.Assert(ok, check.Equals, true)
+			c.Check(o.Start(), check.Equals, i)
+			c.Check(o.End(), check.Equals, i+l)
 		}
+		_, ok := t.FindExact(i, i+6)
+		c.Check(ok, check.Equals, false)
+		_, ok = t.FindExact(i, i)
+		c.Check(ok, check.Equals, false)
 	}
-	l, _ := t.Floor(&overlap{start: min - 1, end: min})
-	c.Check(l, check.DeepEquals, Interface(nil))
+	_, ok = t.FindExact(compInt(-1), compInt(1))
+	c.Check(ok, check.Equals, false)
 }
 
-func (s *S) TestCeil(c *check.C) {
-	min, max := compInt(0), compInt(1000)
+func (s *S) TestGetN(c *check.C) {
 	t := &Tree{}
-	for i := min; i <= max; i++ {
-		if i&1 == 1 { // Insert odd numbers only.
-			t.Insert(&overlap{start: i, end: i + 1}, false)
-		}
-	}
-	for i := min; i < max; i++ {
-		u, _ := t.Ceil(&overlap{start: i, end: i + 1})
-		if i&1 == 1 {
-			c.Check(u, check.DeepEquals, &overlap{start: i, end: i + 1}) // Check odd Ceils are themselves.
-		} else {
-			c.Check(u, check.DeepEquals, &overlap{start: i + 1, end: i + 2}) // Check even Ceils are the next number.
+	for i := compInt(0); i < 100; i++ {
+		t.Insert(&overlap{start: i, end: i + 10, id: uintptr(i)}, false)
+	}
+	for _, q := range []*overlap{{start: 0, end: 100}, {start: 20, end: 25}, {start: 200, end: 300}} {
+		all := t.Get(q)
+		for n := 0; n <= len(all)+1; n++ {
+			got, err := t.GetN(q, n)
+			c.Check(err, check.Equals, nil)
+			want := all
+			if n < len(want) {
+				want = want[:n]
+			}
+			if len(want) == 0 {
+				want = nil
+			}
+			c.Check(got, check.DeepEquals, want, check.Commentf("q=%v n=%d", q, n))
 		}
 	}
-	u, _ := t.Ceil(&overlap{start: max, end: max + 2})
-	c.Check(u, check.DeepEquals, Comparable(nil))
+	_, err := t.GetN(nil, 5)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, err = t.GetN(&overlap{start: 10, end: 5}, 5)
+	c.Check(err, check.Equals, ErrInvertedRange)
 }
 
-func (s *S) TestRandomlyInsertedGet(c *check.C) {
+func (s *S) TestEqual(c *check.C) {
 	var (
-		count, max = 1000, 1000
-		t          = &Tree{}
-		length     = compInt(100)
-		verify     = map[overlap]struct{}{}
-		verified   = map[overlap]struct{}{}
+		t, u  = &Tree{}, &Tree{}
+		equal = func(a, b Interface) bool {
+			ao, bo := a.(*overlap), b.(*overlap)
+			return ao.start == bo.start && ao.end == bo.end
+		}
 	)
-	for i := 0; i < count; i++ {
-		s := compInt(rand.Intn(max))
-		v := overlap{start: s, end: s + length}
-		t.Insert(&v, false)
-		verify[v] = struct{}{}
+	c.Check(t.Equal(u, equal), check.Equals, true)
+	for i := compInt(0); i < 100; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+		u.Insert(&overlap{start: 99 - i, end: 104 - i, id: uintptr(99 - i)}, i%2 == 0)
+	}
+	c.Check(t.Equal(u, equal), check.Equals, true)
+	c.Check(u.Equal(t, equal), check.Equals, true)
+
+	u.DeleteMax(false)
+	c.Check(t.Equal(u, equal), check.Equals, false)
+	u.Insert(&overlap{start: 99, end: 105, id: 99}, false)
+	c.Check(t.Equal(u, equal), check.Equals, false)
+	c.Check(t.Equal(&Tree{}, equal), check.Equals, false)
+}
+
+func (s *S) TestDoWithMeta(c *check.C) {
+	t := &Tree{}
+	c.Check(t.DoWithMeta(func(Interface, int, llrb.Color) bool { return true }), check.Equals, false)
+	for i := compInt(0); i < 100; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
 	}
-	// Random fetch order.
-	for v := range verify {
-		o := t.Get(&v)
-		c.Check(len(o), check.Not(check.Equals), 0) // Check inserted elements are present.
-		for _, iv := range o {
-			vr := *iv.(*overlap)
-			_, ok := verify[vr]
-			c.Check(ok, check.Equals, true, check.Commentf("%v should exist", vr))
-			if ok {
-				verified[vr] = struct{}{}
-			}
+	var (
+		elems    []Interface
+		maxDepth int
+		reds     int
+	)
+	t.DoWithMeta(func(e Interface, depth int, color llrb.Color) (done bool) {
+		elems = append(elems, e)
+		if depth == 0 {
+			c.Check(color, check.Equals, llrb.Black)
 		}
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		if color == llrb.Red {
+			reds++
+		}
+		return
+	})
+	var want []Interface
+	t.Do(func(e Interface) (done bool) { want = append(want, e); return })
+	c.Check(elems, check.DeepEquals, want)
+	c.Check(maxDepth, check.Equals, t.Height()-1)
+
+	var wantReds int
+	var count func(*Node)
+	count = func(n *Node) {
+		if n == nil {
+			return
+		}
+		if n.Color == llrb.Red {
+			wantReds++
+		}
+		count(n.Left)
+		count(n.Right)
 	}
-	c.Check(len(verify), check.Equals, len(verified))
-	for v := range verify {
-		_, ok := verified[v]
-		c.Check(ok, check.Equals, true, check.Commentf("%v should exist", v))
-	}
+	count(t.Root)
+	c.Check(reds, check.Equals, wantReds)
 
-	// Check all possible insertions.
-	for s := compInt(0); s <= compInt(max); s++ {
-		v := overlap{start: s, end: s + length}
-		o := t.Get(&v)
-		if _, ok := verify[v]; ok {
-			c.Check(len(o), check.Not(check.Equals), 0) // Check inserted elements are present.
+	var n int
+	c.Check(t.DoWithMeta(func(Interface, int, llrb.Color) bool { n++; return n == 10 }), check.Equals, true)
+	c.Check(n, check.Equals, 10)
+}
+
+func (s *S) TestMaxEnd(c *check.C) {
+	t := &Tree{}
+	c.Check(t.MaxEnd(), check.Equals, nil)
+	wide := &overlap{start: 0, end: 1000, id: 0}
+	t.Insert(wide, false)
+	for i := compInt(1); i < 100; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	c.Check(t.Max().Start(), check.Equals, compInt(99))
+	c.Check(t.MaxEnd(), check.Equals, Interface(wide))
+	t.Delete(wide, false)
+	c.Check(t.MaxEnd().End(), check.Equals, compInt(104))
+
+	for i := 0; i < 10; i++ {
+		u := &Tree{}
+		for j := 0; j < 100; j++ {
+			s := compInt(rand.Intn(1000))
+			u.Insert(&overlap{start: s, end: s + compInt(rand.Intn(100)), id: uintptr(j)}, false)
 		}
+		var want Interface
+		u.Do(func(e Interface) (done bool) {
+			if want == nil || e.End().Compare(want.End()) > 0 {
+				want = e
+			}
+			return
+		})
+		c.Check(u.MaxEnd(), check.Equals, want)
 	}
 }
 
-func (s *S) TestRandomInsertion(c *check.C) {
+func (s *S) TestGaps(c *check.C) {
+	type gap struct{ start, end compInt }
+	t := &Tree{}
+	for i, iv := range []overlap{{start: 2, end: 4}, {start: 3, end: 6}, {start: 4, end: 5}, {start: 8, end: 10}, {start: 10, end: 12}, {start: 15, end: 20}} {
+		t.Insert(&overlap{start: iv.start, end: iv.end, id: uintptr(i)}, false)
+	}
+	for _, test := range []struct {
+		bound overlap
+		gaps  []gap
+	}{
+		{bound: overlap{start: 0, end: 25}, gaps: []gap{{0, 2}, {6, 8}, {12, 15}, {20, 25}}},
+		{bound: overlap{start: 3, end: 16}, gaps: []gap{{6, 8}, {12, 15}}},
+		{bound: overlap{start: 4, end: 5}, gaps: nil},
+		{bound: overlap{start: 12, end: 15}, gaps: []gap{{12, 15}}},
+		{bound: overlap{start: 30, end: 40}, gaps: []gap{{30, 40}}},
+		{bound: overlap{start: 7, end: 7}, gaps: nil},
+	} {
+		var got []gap
+		bound := test.bound
+		err := t.Gaps(&bound, func(start, end Comparable) (done bool) {
+			got = append(got, gap{start.(compInt), end.(compInt)})
+			return
+		})
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.DeepEquals, test.gaps, check.Commentf("bound: %v", &bound))
+	}
+
+	var n int
+	t.Gaps(&overlap{start: 0, end: 25}, func(start, end Comparable) (done bool) { n++; return true })
+	c.Check(n, check.Equals, 1)
+
+	c.Check(t.Gaps(&overlap{start: 1, end: 0}, nil), check.Equals, ErrInvertedRange)
+	var got []gap
+	(&Tree{}).Gaps(&overlap{start: 1, end: 3}, func(start, end Comparable) (done bool) {
+		got = append(got, gap{start.(compInt), end.(compInt)})
+		return
+	})
+	c.Check(got, check.DeepEquals, []gap{{1, 3}})
+}
+
+func (s *S) TestMinGap(c *check.C) {
+	dist := func(a, b Comparable) float64 { return float64(b.(compInt) - a.(compInt)) }
+	t := &Tree{}
+	_, _, _, ok := t.MinGap(dist)
+	c.Check(ok, check.Equals, false)
+
+	var ivs []*overlap
+	for i, iv := range []overlap{{start: 0, end: 20}, {start: 2, end: 4}, {start: 21, end: 25}, {start: 25, end: 30}, {start: 33, end: 40}, {start: 43, end: 44}} {
+		ivs = append(ivs, &overlap{start: iv.start, end: iv.end, id: uintptr(i)})
+		t.Insert(ivs[i], false)
+	}
+	gap, before, after, ok := t.MinGap(dist)
+	c.Check(ok, check.Equals, true)
+	c.Check(gap, check.Equals, 1.)
+	c.Check(before, check.Equals, Interface(ivs[0]))
+	c.Check(after, check.Equals, Interface(ivs[2]))
+
+	t.Delete(ivs[2], false)
+	gap, before, after, ok = t.MinGap(dist)
+	c.Check(ok, check.Equals, true)
+	c.Check(gap, check.Equals, 3.)
+	c.Check(before, check.Equals, Interface(ivs[3]))
+	c.Check(after, check.Equals, Interface(ivs[4]))
+
+	t = &Tree{}
+	t.Insert(&overlap{start: 0, end: 5}, false)
+	t.Insert(&overlap{start: 5, end: 9, id: 1}, false)
+	_, _, _, ok = t.MinGap(dist)
+	c.Check(ok, check.Equals, false)
+}
+
+func (s *S) TestHasOverlap(c *check.C) {
+	t := &Tree{}
+	_, _, ok := t.HasOverlap()
+	c.Check(ok, check.Equals, false)
+
+	var ivs []*overlap
+	for i, iv := range []overlap{{start: 0, end: 20}, {start: 20, end: 25}, {start: 25, end: 30}, {start: 33, end: 40}} {
+		ivs = append(ivs, &overlap{start: iv.start, end: iv.end, id: uintptr(i)})
+		t.Insert(ivs[i], false)
+	}
+	_, _, ok = t.HasOverlap()
+	c.Check(ok, check.Equals, false)
+
+	nested := &overlap{start: 5, end: 10, id: 10}
+	t.Insert(nested, false)
+	a, b, ok := t.HasOverlap()
+	c.Check(ok, check.Equals, true)
+	c.Check(a, check.Equals, Interface(ivs[0]))
+	c.Check(b, check.Equals, Interface(nested))
+
+	t.Delete(nested, false)
+	late := &overlap{start: 39, end: 45, id: 11}
+	t.Insert(late, false)
+	a, b, ok = t.HasOverlap()
+	c.Check(ok, check.Equals, true)
+	c.Check(a, check.Equals, Interface(ivs[3]))
+	c.Check(b, check.Equals, Interface(late))
+}
+
+func (s *S) TestStab(c *check.C) {
 	var (
-		count, max = 1000, 1000
-		t          = &Tree{}
-		length     = compInt(100)
+		t      = &Tree{}
+		length = compInt(10)
 	)
-	for i := 0; i < count; i++ {
-		s := compInt(rand.Intn(max))
-		v := overlap{start: s, end: s + length}
-		t.Insert(&v, false)
-		failed := false
-		failed = failed || !c.Check(t.isBST(), check.Equals, true)
-		failed = failed || !c.Check(t.is23_234(), check.Equals, true)
-		failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
-		failed = failed || !c.Check(t.isRanged(), check.Equals, true)
-		if failed {
-			if *printTree {
-				c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
-			}
-			if *genDot && t.Len() <= *dotLimit {
-				err := t.dotFile(fmt.Sprintf("TestRandomInsertion_after_ins_%d_%d", v.start, v.end), "")
-				if err != nil {
-					c.Errorf("Dot file write failed: %v", err)
+	c.Check(t.Stab(compInt(0), true), check.DeepEquals, []Interface(nil))
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(int(length))), id: uintptr(i)}, false)
+	}
+	for p := compInt(-1); p <= 1000+length; p++ {
+		for _, closed := range []bool{false, true} {
+			var want []Interface
+			t.Do(func(e Interface) (done bool) {
+				o := e.(*overlap)
+				if o.start <= p && (p < o.end || (closed && p == o.end)) {
+					want = append(want, e)
 				}
-			}
-			c.Fatal("Cannot continue test: invariant contradiction")
+				return
+			})
+			c.Check(t.Stab(p, closed), check.DeepEquals, want, check.Commentf("p=%d closed=%t", p, closed))
 		}
 	}
 }
 
-func (s *S) TestRandomDeletion(c *check.C) {
-	var (
-		count, max = 14, 3
-		r          = make([]overlap, count)
-		t          = &Tree{}
-		length     = compInt(1)
-	)
-	for i := range r {
-		s := compInt(rand.Intn(max))
-		r[i] = overlap{start: s, end: s + length, id: uintptr(i)}
-		t.Insert(&r[i], false)
+func (s *S) TestDepth(c *check.C) {
+	t := &Tree{}
+	c.Check(t.Depth(compInt(0), true), check.Equals, 0)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(20)), id: uintptr(i)}, false)
+	}
+	for p := compInt(-1); p <= 1020; p++ {
+		for _, closed := range []bool{false, true} {
+			c.Check(t.Depth(p, closed), check.Equals, len(t.Stab(p, closed)), check.Commentf("p=%d closed=%t", p, closed))
+		}
 	}
-	for i, v := range r {
-		var dotString string
-		if *genDot && t.Len() <= *dotLimit {
-			dotString = t.dot(fmt.Sprintf("TestRandomDeletion_before_del_%d_%d_%d", i, v.start, v.end))
+}
+
+func (s *S) TestDepthProfile(c *check.C) {
+	t := &Tree{}
+	c.Check(t.DepthProfile(compInt(10), compInt(0), nil), check.Equals, ErrInvertedRange)
+	var runs int
+	c.Check(t.DepthProfile(compInt(0), compInt(10), func(start, end Comparable, depth int) (done bool) {
+		c.Check(start, check.Equals, compInt(0))
+		c.Check(end, check.Equals, compInt(10))
+		c.Check(depth, check.Equals, 0)
+		runs++
+		return
+	}), check.Equals, nil)
+	c.Check(runs, check.Equals, 1)
+
+	for i := 0; i < 200; i++ {
+		s := compInt(rand.Intn(500))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(30)), id: uintptr(i)}, false)
+	}
+	for _, b := range []struct{ lo, hi compInt }{{0, 540}, {-10, 600}, {100, 200}, {250, 251}, {300, 300}} {
+		var (
+			depth = make([]int, b.hi-b.lo)
+			pos   = b.lo
+			last  = -1
+		)
+		c.Check(t.DepthProfile(b.lo, b.hi, func(start, end Comparable, d int) (done bool) {
+			c.Check(start, check.Equals, pos)
+			c.Check(end.(compInt) > pos, check.Equals, true)
+			c.Check(d, check.Not(check.Equals), last)
+			for p := start.(compInt); p < end.(compInt); p++ {
+				depth[p-b.lo] = d
+			}
+			pos, last = end.(compInt), d
+			return
+		}), check.Equals, nil)
+		if b.lo != b.hi {
+			c.Check(pos, check.Equals, b.hi)
 		}
-		t.Delete(&v, false)
-		if t != nil {
-			failed := false
-			failed = failed || !c.Check(t.isBST(), check.Equals, true)
-			failed = failed || !c.Check(t.is23_234(), check.Equals, true)
-			failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
-			failed = failed || !c.Check(t.isRanged(), check.Equals, true)
-			if failed {
-				if *printTree {
-					c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
-				}
-				if *genDot && t.Len() <= *dotLimit {
-					var err error
-					err = (*Tree)(nil).dotFile(fmt.Sprintf("TestRandomDeletion_before_del_%d_%d_%d", i, v.start, v.end), dotString)
-					if err != nil {
-						c.Errorf("Dot file write failed: %v", err)
-					}
-					err = t.dotFile(fmt.Sprintf("TestRandomDeletion_after_del_%d_%d_%d", i, v.start, v.end), "")
-					if err != nil {
-						c.Errorf("Dot file write failed: %v", err)
-					}
-				}
-				c.Fatal("Cannot continue test: invariant contradiction")
+		for p := b.lo; p < b.hi; p++ {
+			c.Check(depth[p-b.lo], check.Equals, t.Depth(p, false), check.Commentf("p=%d", p))
+		}
+	}
+
+	runs = 0
+	t.DepthProfile(compInt(0), compInt(540), func(_, _ Comparable, _ int) (done bool) {
+		runs++
+		return runs == 3
+	})
+	c.Check(runs, check.Equals, 3)
+}
+
+func (s *S) TestDoChunked(c *check.C) {
+	t := &Tree{}
+	chunked := func(n int, fn func([]Interface) bool) bool {
+		done, err := t.DoChunked(n, fn)
+		c.Check(err, check.Equals, nil)
+		return done
+	}
+	c.Check(chunked(10, func([]Interface) (done bool) { c.Error("unexpected batch"); return }), check.Equals, false)
+	for i := 0; i < 25; i++ {
+		t.Insert(&overlap{start: compInt(i), end: compInt(i + 1), id: uintptr(i)}, false)
+	}
+	for _, n := range []int{1, 5, 7, 25, 100} {
+		var (
+			got   []Interface
+			sizes []int
+		)
+		c.Check(chunked(n, func(batch []Interface) (done bool) {
+			got = append(got, batch...)
+			sizes = append(sizes, len(batch))
+			return
+		}), check.Equals, false)
+		c.Check(got, check.DeepEquals, t.Slice())
+		for i, l := range sizes {
+			if i < len(sizes)-1 {
+				c.Check(l, check.Equals, n)
+			} else {
+				c.Check(l > 0 && l <= n, check.Equals, true)
 			}
 		}
 	}
-	c.Check(*t, check.DeepEquals, Tree{})
+
+	var calls int
+	c.Check(chunked(10, func([]Interface) (done bool) { calls++; return true }), check.Equals, true)
+	c.Check(calls, check.Equals, 1)
+	calls = 0
+	c.Check(chunked(10, func([]Interface) (done bool) { calls++; return calls == 3 }), check.Equals, true)
+	c.Check(calls, check.Equals, 3)
+	_, err := t.DoChunked(0, nil)
+	c.Check(err, check.Equals, ErrInvalidChunkSize)
 }
 
-func (s *S) TestDeleteMinMax(c *check.C) {
+func (s *S) TestDoErr(c *check.C) {
+	t := &Tree{}
+	c.Check(t.DoErr(func(Interface) error { return errors.New("called") }), check.Equals, nil)
+	for i := compInt(0); i < 100; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	var n int
+	c.Check(t.DoErr(func(Interface) error { n++; return nil }), check.Equals, nil)
+	c.Check(n, check.Equals, 100)
+
 	var (
-		min, max = compInt(0), compInt(10)
-		t        = &Tree{}
-		length   = compInt(1)
-		dI       int
+		errStop = errors.New("stop")
+		last    Interface
 	)
-	for i := min; i <= max; i++ {
-		v := overlap{start: i, end: i + length}
-		t.Insert(&v, false)
-		dI = t.Len()
-	}
-	c.Check(dI, check.Equals, int(max-min+1))
-	for i, m := 0, int(max); i < m/2; i++ {
+	n = 0
+	c.Check(t.DoErr(func(e Interface) error {
+		n++
+		last = e
+		if e.Start().(compInt) == 42 {
+			return errStop
+		}
+		return nil
+	}), check.Equals, errStop)
+	c.Check(n, check.Equals, 43)
+	c.Check(last.Start(), check.Equals, compInt(42))
+}
+
+func (s *S) TestDoContext(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 1000; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	var n int
+	done, err := t.DoContext(context.Background(), func(Interface) (done bool) { n++; return })
+	c.Check(done, check.Equals, false)
+	c.Check(err, check.Equals, nil)
+	c.Check(n, check.Equals, 1000)
+
+	n = 0
+	done, err = t.DoContext(context.Background(), func(Interface) (done bool) { n++; return n == 10 })
+	c.Check(done, check.Equals, true)
+	c.Check(err, check.Equals, nil)
+	c.Check(n, check.Equals, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n = 0
+	done, err = t.DoContext(ctx, func(Interface) (done bool) {
+		n++
+		if n == 100 {
+			cancel()
+		}
+		return
+	})
+	c.Check(done, check.Equals, true)
+	c.Check(err, check.Equals, context.Canceled)
+	c.Check(n < 100+contextCheckInterval, check.Equals, true)
+
+	n = 0
+	done, err = t.DoContext(ctx, func(Interface) (done bool) { n++; return })
+	c.Check(done, check.Equals, true)
+	c.Check(err, check.Equals, context.Canceled)
+	c.Check(n, check.Equals, 0)
+}
+
+func (s *S) TestBounds(c *check.C) {
+	t := &Tree{}
+	start, end, ok := t.Bounds()
+	c.Check(start, check.Equals, nil)
+	c.Check(end, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+
+	t.Insert(&overlap{start: 10, end: 100, id: 0}, false)
+	for i := compInt(1); i < 50; i++ {
+		t.Insert(&overlap{start: i + 5, end: i + 10, id: uintptr(i)}, false)
+	}
+	start, end, ok = t.Bounds()
+	c.Check(start, check.Equals, compInt(6))
+	c.Check(end, check.Equals, compInt(100))
+	c.Check(ok, check.Equals, true)
+
+	t.Delete(&overlap{start: 10, end: 100, id: 0}, false)
+	t.DeleteMin(false)
+	start, end, ok = t.Bounds()
+	c.Check(start, check.Equals, compInt(7))
+	c.Check(end, check.Equals, compInt(59))
+	c.Check(ok, check.Equals, true)
+}
+
+func (s *S) TestFix(c *check.C) {
+	var (
+		t     = &Tree{}
+		elems []*overlap
+	)
+	for i := compInt(0); i < 100; i++ {
+		e := &overlap{start: i, end: i + 5, id: uintptr(i)}
+		elems = append(elems, e)
+		t.Insert(e, false)
+	}
+
+	e := elems[10]
+	e.start, e.end = 200, 300
+	c.Check(t.Fix(e), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 100)
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Max(), check.Equals, Interface(e))
+	c.Check(t.Get(&overlap{start: 250, end: 251}), check.DeepEquals, []Interface{e})
+
+	c.Check(t.Fix(&overlap{start: 0, end: 1, id: 1000}), check.Equals, ErrNotFound)
+	c.Check(t.Fix(&overlap{start: 1, end: 0, id: 1}), check.Equals, ErrInvertedRange)
+	c.Check(t.Len(), check.Equals, 100)
+
+	for i, e := range elems {
+		e.start, e.end = compInt(1000-i), compInt(1000-i+rand.Intn(10))
+	}
+	t.FixAll()
+	c.Check(t.Len(), check.Equals, 100)
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Min(), check.Equals, Interface(elems[99]))
+	c.Check(t.Max(), check.Equals, Interface(elems[0]))
+
+	t = &Tree{}
+	t.FixAll()
+	c.Check(t.Len(), check.Equals, 0)
+}
+
+func (s *S) TestSplit(c *check.C) {
+	for _, k := range []compInt{-10, 0, 37, 50, 99, 200} {
+		var (
+			t     = &Tree{}
+			elems []Interface
+		)
+		for i := 0; i < 1000; i++ {
+			s := compInt(rand.Intn(100))
+			t.Insert(&overlap{start: s, end: s + 10, id: uintptr(i)}, false)
+		}
+		t.Do(func(e Interface) (done bool) { elems = append(elems, e); return })
+
+		l, r := t.Split(k)
+		c.Check(t.Len(), check.Equals, 0)
+		c.Check(t.Root, check.Equals, (*Node)(nil))
+		c.Check(l.Validate(), check.Equals, nil)
+		c.Check(r.Validate(), check.Equals, nil)
+		c.Check(l.Len()+r.Len(), check.Equals, len(elems))
+
+		var got []Interface
+		l.Do(func(e Interface) (done bool) {
+			c.Check(e.Start().Compare(k) < 0, check.Equals, true)
+			got = append(got, e)
+			return
+		})
+		r.Do(func(e Interface) (done bool) {
+			c.Check(e.Start().Compare(k) >= 0, check.Equals, true)
+			got = append(got, e)
+			return
+		})
+		c.Check(got, check.DeepEquals, elems)
+	}
+}
+
+func (s *S) TestSlice(c *check.C) {
+	t := &Tree{}
+	c.Check(t.Slice(), check.DeepEquals, []Interface{})
+	c.Check(t.SliceReverse(), check.DeepEquals, []Interface{})
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(100))
+		t.Insert(&overlap{start: s, end: s + 10, id: uintptr(i)}, false)
+	}
+	var fwd, rev []Interface
+	t.Do(func(e Interface) (done bool) { fwd = append(fwd, e); return })
+	t.DoReverse(func(e Interface) (done bool) { rev = append(rev, e); return })
+	got := t.Slice()
+	c.Check(got, check.DeepEquals, fwd)
+	c.Check(cap(got), check.Equals, t.Len())
+	c.Check(t.SliceReverse(), check.DeepEquals, rev)
+}
+
+func (s *S) TestCheckDepth(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 1000; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	t.checkDepth()
+
+	// Build a degenerate chain of nodes.
+	var root *Node
+	for i := compInt(0); i < 100; i++ {
+		root = &Node{Elem: &overlap{start: i, end: i + 1, id: uintptr(i)}, Left: root}
+	}
+	t = &Tree{Root: root, Count: 100}
+	c.Check(func() { t.checkDepth() }, check.PanicMatches, "interval: comparator appears inconsistent")
+
+	// A cycle must be detected without overflowing the stack.
+	root.Left.Left = root
+	c.Check(func() { t.checkDepth() }, check.PanicMatches, "interval: comparator appears inconsistent")
+}
+
+func (s *S) TestInsertBatch(c *check.C) {
+	var (
+		t     = &Tree{}
+		elems []Interface
+	)
+	for i := compInt(0); i < 100; i++ {
+		elems = append(elems, &overlap{start: i, end: i + 5, id: uintptr(i)})
+	}
+	c.Check(t.InsertBatch(elems, false), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 100)
+	c.Check(t.Validate(), check.Equals, nil)
+
+	u := &Tree{}
+	bad := append([]Interface(nil), elems...)
+	bad[3] = &overlap{start: 10, end: 5, id: 3}
+	err := u.InsertBatch(bad, false)
+	c.Check(err, check.DeepEquals, &BatchError{Index: 3, Err: ErrInvertedRange})
+	c.Check(err, check.ErrorMatches, "interval: batch element 3: interval: inverted range")
+	c.Check(u.Len(), check.Equals, 0)
+	c.Check(u.Root, check.Equals, (*Node)(nil))
+
+	bad[3] = elems[3]
+	bad[7] = nil
+	c.Check(u.InsertBatch(bad, false), check.DeepEquals, &BatchError{Index: 7, Err: ErrNilOverlapper})
+	c.Check(u.Len(), check.Equals, 0)
+}
+
+func (s *S) TestDeleteElem(c *check.C) {
+	var (
+		t     = &Tree{}
+		elems []*overlap
+		same  = func(a, b Interface) bool { return a.ID() == b.ID() }
+	)
+	for i := 0; i < 10; i++ {
+		e := &overlap{start: 0, end: 10, id: uintptr(i)}
+		elems = append(elems, e)
+		t.Insert(e, false)
+	}
+	ok, err := t.DeleteElem(elems[6], same, false)
+	c.Check(ok, check.Equals, true)
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 9)
+	c.Check(t.Validate(), check.Equals, nil)
+	for _, e := range t.Get(&overlap{start: 0, end: 10}) {
+		c.Check(e.ID(), check.Not(check.Equals), uintptr(6))
+	}
+
+	ok, err = t.DeleteElem(elems[6], same, false)
+	c.Check(ok, check.Equals, false)
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 9)
+
+	ok, err = t.DeleteElem(&overlap{start: 1, end: 0}, same, false)
+	c.Check(ok, check.Equals, false)
+	c.Check(err, check.Equals, ErrInvertedRange)
+
+	for i, e := range elems {
+		if i == 6 {
+			continue
+		}
+		ok, _ := t.DeleteElem(e, same, false)
+		c.Check(ok, check.Equals, true)
+	}
+	c.Check(t.Len(), check.Equals, 0)
+	c.Check(t.Root, check.Equals, (*Node)(nil))
+}
+
+func (s *S) TestIntersection(c *check.C) {
+	for _, n := range []int{0, 10, 100, 1000} {
+		t, u := &Tree{}, &Tree{}
+		for i := 0; i < 300; i++ {
+			s := compInt(rand.Intn(1000))
+			t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(10)), id: uintptr(i)}, false)
+		}
+		for i := 0; i < n; i++ {
+			s := compInt(rand.Intn(1000))
+			u.Insert(&overlap{start: s, end: s + compInt(rand.Intn(10)), id: uintptr(i)}, false)
+		}
+		var want []Interface
+		t.Do(func(e Interface) (done bool) {
+			for _, q := range u.Slice() {
+				if e.Overlap(q) {
+					want = append(want, e)
+					break
+				}
+			}
+			return
+		})
+		c.Check(t.Intersection(u), check.DeepEquals, want, check.Commentf("n=%d", n))
+	}
+}
+
+func (s *S) TestDifference(c *check.C) {
+	for _, n := range []int{0, 10, 100, 1000} {
+		t, u := &Tree{}, &Tree{}
+		for i := 0; i < 300; i++ {
+			s := compInt(rand.Intn(1000))
+			t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(10)), id: uintptr(i)}, false)
+		}
+		for i := 0; i < n; i++ {
+			s := compInt(rand.Intn(1000))
+			u.Insert(&overlap{start: s, end: s + compInt(rand.Intn(10)), id: uintptr(i)}, false)
+		}
+		var want []Interface
+		t.Do(func(e Interface) (done bool) {
+			for _, q := range u.Slice() {
+				if e.Overlap(q) {
+					return
+				}
+			}
+			want = append(want, e)
+			return
+		})
+		got := t.Difference(u)
+		c.Check(got, check.DeepEquals, want, check.Commentf("n=%d", n))
+		c.Check(len(got)+len(t.Intersection(u)), check.Equals, t.Len())
+	}
+}
+
+func (s *S) TestNilOverlapper(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 10; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	c.Check(t.Insert(nil, false), check.Equals, ErrNilOverlapper)
+	c.Check(t.Delete(nil, false), check.Equals, ErrNilOverlapper)
+	c.Check(t.Len(), check.Equals, 10)
+	c.Check(t.Get(nil), check.DeepEquals, []Interface(nil))
+	c.Check(t.DoMatching(func(Interface) bool { return true }, nil), check.Equals, false)
+	o, err := t.Floor(nil)
+	c.Check(o, check.Equals, nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	o, err = t.Ceil(nil)
+	c.Check(o, check.Equals, nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	o, err = t.Predecessor(nil)
+	c.Check(o, check.Equals, nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	o, err = t.Successor(nil)
+	c.Check(o, check.Equals, nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+
+	equal := func(a, b Interface) bool { return true }
+	_, err = t.InsertOrReplace(nil, equal, false)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, err = t.DeleteElem(nil, equal, false)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	c.Check(t.Fix(nil), check.Equals, ErrNilOverlapper)
+	c.Check(t.Replace(nil, t.Min()), check.Equals, ErrNilOverlapper)
+	c.Check(t.Replace(t.Min(), nil), check.Equals, ErrNilOverlapper)
+	c.Check(t.Gaps(nil, func(_, _ Comparable) bool { return false }), check.Equals, ErrNilOverlapper)
+	c.Check(t.Len(), check.Equals, 10)
+	c.Check(t.DoMatchingReverse(func(Interface) bool { return true }, nil), check.Equals, false)
+	c.Check(t.Contained(nil), check.DeepEquals, []Interface(nil))
+	c.Check(t.Containing(nil), check.DeepEquals, []Interface(nil))
+	c.Check(t.WeightOverlapping(nil), check.Equals, 0.)
+	c.Check(t.Rank(nil), check.Equals, 0)
+	o, ok := t.Next(nil, equal)
+	c.Check(o, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	o, ok = t.MatchCursor(nil).Next()
+	c.Check(o, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+}
+
+func (s *S) TestGetLimited(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 1000; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	q := &overlap{start: 0, end: 2000}
+	o, truncated, err := t.GetLimited(q, 2000)
+	c.Check(err, check.Equals, nil)
+	c.Check(truncated, check.Equals, false)
+	c.Check(o, check.DeepEquals, t.Get(q))
+
+	o, truncated, _ = t.GetLimited(q, 1000)
+	c.Check(truncated, check.Equals, false)
+	c.Check(len(o), check.Equals, 1000)
+
+	all := t.Get(q)
+	for _, limit := range []int{0, 1, 10, 100, 999} {
+		o, truncated, _ = t.GetLimited(q, limit)
+		c.Check(truncated, check.Equals, true)
+		c.Check(len(o) <= limit, check.Equals, true)
+		for i, e := range o {
+			c.Check(e, check.Equals, all[i])
+		}
+	}
+
+	q = &overlap{start: 500, end: 501}
+	o, truncated, _ = t.GetLimited(q, 30)
+	c.Check(truncated, check.Equals, false)
+	c.Check(o, check.DeepEquals, t.Get(q))
+
+	o, truncated, _ = t.GetLimited(&overlap{start: 5000, end: 5001}, 0)
+	c.Check(o, check.DeepEquals, []Interface(nil))
+	c.Check(truncated, check.Equals, false)
+
+	_, _, err = t.GetLimited(nil, 10)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, _, err = t.GetLimited(&overlap{start: 10, end: 5}, 10)
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestRebalance(c *check.C) {
+	t := &Tree{}
+	t.Rebalance()
+	c.Check(t.Root, check.Equals, (*Node)(nil))
+	for i := compInt(0); i < 10000; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	for i := 0; i < 9000; i++ {
+		t.DeleteMin(false)
+	}
+	want := t.Slice()
+	h := t.Height()
+	t.Rebalance()
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Slice(), check.DeepEquals, want)
+	c.Check(t.Height() <= h, check.Equals, true)
+	c.Check(t.Height() <= 2*int(math.Ceil(math.Log2(float64(t.Len()+1)))), check.Equals, true)
+}
+
+func (s *S) TestFirstOverlap(c *check.C) {
+	t := &Tree{}
+	o, ok := t.FirstOverlap(&overlap{start: 0, end: 10})
+	c.Check(o, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(20)), id: uintptr(i)}, false)
+	}
+	for s := compInt(-10); s < 1030; s += 3 {
+		q := &overlap{start: s, end: s + 2}
+		all := t.Get(q)
+		o, ok := t.FirstOverlap(q)
+		if len(all) == 0 {
+			c.Check(ok, check.Equals, false)
+			c.Check(o, check.Equals, nil)
+			continue
+		}
+		c.Check(ok, check.Equals, true)
+		c.Check(o, check.Equals, all[0])
+	}
+}
+
+func (s *S) TestMap(c *check.C) {
+	t := &Tree{}
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(20)), id: uintptr(i)}, false)
+	}
+	orig := t.Slice()
+
+	shift := func(e Interface) Interface {
+		o := e.(*overlap)
+		return &overlap{start: o.start + 100, end: o.end + 100, id: o.id}
+	}
+	u, err := t.Map(shift)
+	c.Assert(err, check.Equals, nil)
+	c.Check(u.Validate(), check.Equals, nil)
+	c.Check(u.Len(), check.Equals, t.Len())
+	c.Check(t.Slice(), check.DeepEquals, orig)
+	for i, e := range u.Slice() {
+		c.Check(e.Start(), check.Equals, orig[i].Start().(compInt)+100)
+		c.Check(e.ID(), check.Equals, orig[i].ID())
+	}
+
+	mirror := func(e Interface) Interface {
+		o := e.(*overlap)
+		return &overlap{start: -o.end, end: -o.start, id: o.id}
+	}
+	u, err = t.Map(mirror)
+	c.Assert(err, check.Equals, nil)
+	c.Check(u.Validate(), check.Equals, nil)
+	c.Check(u.Len(), check.Equals, t.Len())
+
+	collapse := func(e Interface) Interface { return &overlap{start: 0, end: 1, id: 0} }
+	u, err = t.Map(collapse)
+	c.Check(u, check.Equals, (*Tree)(nil))
+	c.Check(err, check.Equals, ErrDuplicate)
+	c.Check(t.Slice(), check.DeepEquals, orig)
+
+	invert := func(e Interface) Interface { return &overlap{start: 1, end: 0} }
+	u, err = t.Map(invert)
+	c.Check(u, check.Equals, (*Tree)(nil))
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestFilter(c *check.C) {
+	t := &Tree{}
+	c.Check(t.Filter(func(Interface) bool { return false }), check.Equals, 0)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(20)), id: uintptr(i)}, false)
+	}
+	var want []Interface
+	for _, e := range t.Slice() {
+		if e.ID()%3 != 0 {
+			want = append(want, e)
+		}
+	}
+	n := t.Filter(func(e Interface) bool { return e.ID()%3 != 0 })
+	c.Check(n, check.Equals, 1000-len(want))
+	c.Check(t.Len(), check.Equals, len(want))
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Slice(), check.DeepEquals, want)
+
+	c.Check(t.Filter(func(Interface) bool { return true }), check.Equals, 0)
+	c.Check(t.Slice(), check.DeepEquals, want)
+	c.Check(t.Filter(func(Interface) bool { return false }), check.Equals, len(want))
+	c.Check(t.Len(), check.Equals, 0)
+	c.Check(t.Root, check.Equals, (*Node)(nil))
+}
+
+func (s *S) TestGetLastN(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 100; i++ {
+		t.Insert(&overlap{start: i, end: i + 10, id: uintptr(i)}, false)
+	}
+	for _, q := range []*overlap{{start: 0, end: 100}, {start: 20, end: 25}, {start: 200, end: 300}} {
+		var rev []Interface
+		t.DoMatchingReverse(func(e Interface) (done bool) { rev = append(rev, e); return }, q)
+		all := t.Get(q)
+		c.Assert(len(rev), check.Equals, len(all))
+		for i, e := range rev {
+			c.Check(e, check.Equals, all[len(all)-1-i])
+		}
+		for n := 0; n <= len(rev)+1; n++ {
+			want := rev
+			if n < len(want) {
+				want = want[:n]
+			}
+			if len(want) == 0 {
+				want = nil
+			}
+			got, err := t.GetLastN(q, n)
+			c.Check(err, check.Equals, nil)
+			c.Check(got, check.DeepEquals, want, check.Commentf("q=%v n=%d", q, n))
+		}
+	}
+	_, err := t.GetLastN(nil, 5)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, err = t.GetLastN(&overlap{start: 10, end: 5}, 5)
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestContainment(c *check.C) {
+	t := &Tree{}
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(50)), id: uintptr(i)}, false)
+	}
+	for _, q := range []*overlap{{start: 100, end: 150}, {start: 500, end: 500}, {start: 0, end: 1100}, {start: 300, end: 305}, {start: -10, end: -5}} {
+		var contained, containing []Interface
+		t.Do(func(e Interface) (done bool) {
+			o := e.(*overlap)
+			if q.start <= o.start && o.end <= q.end {
+				contained = append(contained, e)
+			}
+			if o.start <= q.start && q.end <= o.end {
+				containing = append(containing, e)
+			}
+			return
+		})
+		c.Check(t.Contained(q), check.DeepEquals, contained, check.Commentf("q=%v", q))
+		c.Check(t.Containing(q), check.DeepEquals, containing, check.Commentf("q=%v", q))
+	}
+}
+
+func (s *S) TestPop(c *check.C) {
+	t := &Tree{}
+	e, ok := t.PopMin(false)
+	c.Check(e, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	e, ok = t.PopMax(false)
+	c.Check(e, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(20)), id: uintptr(i)}, false)
+	}
+	want := t.Slice()
+	for i := 0; i < 500; i++ {
+		e, ok := t.PopMin(false)
+		c.Assert(ok, check.Equals, true)
+		c.Check(e, check.Equals, want[i])
+		e, ok = t.PopMax(false)
+		c.Assert(ok, check.Equals, true)
+		c.Check(e, check.Equals, want[len(want)-1-i])
+		if i%50 == 0 {
+			c.Check(t.Validate(), check.Equals, nil)
+		}
+	}
+	c.Check(t.Len(), check.Equals, 0)
+	c.Check(t.Root, check.Equals, (*Node)(nil))
+
+	equalKey := func(a, b Interface) bool {
+		return a.Start().Compare(b.Start()) == 0 && a.End().Compare(b.End()) == 0
+	}
+	for k := 0; k < 3; k++ {
+		for i := compInt(0); i < 10; i++ {
+			t.InsertShared(&overlap{start: i, end: i + 5, id: uintptr(k*10) + uintptr(i)}, equalKey, false)
+		}
+	}
+	want = t.Slice()
+	for i := 0; i < 15; i++ {
+		e, _ := t.PopMin(false)
+		c.Check(e, check.Equals, want[i])
+		e, _ = t.PopMax(false)
+		c.Check(e, check.Equals, want[len(want)-1-i])
+		c.Check(t.Validate(), check.Equals, nil)
+	}
+	c.Check(t.Len(), check.Equals, 0)
+}
+
+func (s *S) TestErrorsIs(c *check.C) {
+	err := (&Tree{}).InsertBatch([]Interface{&overlap{start: 1, end: 0}}, false)
+	c.Check(errors.Is(err, ErrInvertedRange), check.Equals, true)
+	wrapped := fmt.Errorf("loading features: %w", err)
+	c.Check(errors.Is(wrapped, ErrInvertedRange), check.Equals, true)
+	var be *BatchError
+	c.Check(errors.As(wrapped, &be), check.Equals, true)
+	c.Check(be.Index, check.Equals, 0)
+
+	t := &Tree{}
+	for i := compInt(0); i < 10; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	c.Check(t.Validate(), check.Equals, nil)
+	t.Count++
+	err = t.Validate()
+	c.Check(errors.Is(err, ErrInvalidTree), check.Equals, true)
+	c.Check(errors.Is(err, ErrInvertedRange), check.Equals, false)
+}
+
+func (s *S) TestDoMatchingStats(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 1000; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	var got []Interface
+	q := &overlap{start: 500, end: 510}
+	visited, matched, interrupted, err := t.DoMatchingStats(func(e Interface) (done bool) { got = append(got, e); return }, q)
+	c.Check(err, check.Equals, nil)
+	c.Check(got, check.DeepEquals, t.Get(q))
+	c.Check(matched, check.Equals, len(got))
+	c.Check(interrupted, check.Equals, false)
+	c.Check(visited >= matched, check.Equals, true)
+	c.Check(visited < 100, check.Equals, true)
+
+	// A query overlapping every interval visits every node.
+	visited, matched, interrupted, _ = t.DoMatchingStats(func(Interface) (done bool) { return }, &overlap{start: -10, end: 2000})
+	c.Check(visited, check.Equals, 1000)
+	c.Check(matched, check.Equals, 1000)
+	c.Check(interrupted, check.Equals, false)
+
+	_, matched, interrupted, _ = t.DoMatchingStats(func(Interface) (done bool) { return true }, q)
+	c.Check(matched, check.Equals, 1)
+	c.Check(interrupted, check.Equals, true)
+
+	visited, matched, interrupted, _ = t.DoMatchingStats(func(Interface) (done bool) { return }, &overlap{start: 5000, end: 5001})
+	c.Check(visited, check.Equals, 0)
+	c.Check(matched, check.Equals, 0)
+	c.Check(interrupted, check.Equals, false)
+
+	fail := func(Interface) (done bool) { c.Error("unexpected call"); return }
+	_, _, _, err = t.DoMatchingStats(fail, nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, _, _, err = t.DoMatchingStats(fail, &overlap{start: 10, end: 5})
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestDoClusters(c *check.C) {
+	t := &Tree{}
+	c.Check(t.DoClusters(func([]Interface) bool { return true }, false), check.Equals, false)
+	for i, iv := range [][2]compInt{{0, 10}, {2, 3}, {4, 5}, {10, 12}, {12, 14}, {20, 25}, {21, 22}, {30, 30}, {30, 31}} {
+		t.Insert(&overlap{start: iv[0], end: iv[1], id: uintptr(i)}, false)
+	}
+	for _, test := range []struct {
+		touching bool
+		want     [][]string
+	}{
+		{
+			touching: false,
+			want:     [][]string{{"[0,10)", "[2,3)", "[4,5)"}, {"[10,12)"}, {"[12,14)"}, {"[20,25)", "[21,22)"}, {"[30,30)"}, {"[30,31)"}},
+		},
+		{
+			touching: true,
+			want:     [][]string{{"[0,10)", "[2,3)", "[4,5)", "[10,12)", "[12,14)"}, {"[20,25)", "[21,22)"}, {"[30,30)", "[30,31)"}},
+		},
+	} {
+		var got [][]string
+		t.DoClusters(func(cluster []Interface) (done bool) {
+			var s []string
+			for _, e := range cluster {
+				s = append(s, fmt.Sprint(e))
+			}
+			got = append(got, s)
+			return
+		}, test.touching)
+		c.Check(got, check.DeepEquals, test.want, check.Commentf("touching=%t", test.touching))
+	}
+
+	var n int
+	c.Check(t.DoClusters(func([]Interface) bool { n++; return n == 2 }, false), check.Equals, true)
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *S) TestDeleteRange(c *check.C) {
+	t := &Tree{}
+	n, err := t.DeleteRange(compInt(0), compInt(10))
+	c.Check(n, check.Equals, 0)
+	c.Check(err, check.Equals, nil)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(20)), id: uintptr(i)}, false)
+	}
+	_, err = t.DeleteRange(compInt(10), compInt(0))
+	c.Check(err, check.Equals, ErrInvertedRange)
+	c.Check(t.Len(), check.Equals, 1000)
+
+	var want []Interface
+	for _, e := range t.Slice() {
+		if s := e.Start().(compInt); s < 250 || s > 500 {
+			want = append(want, e)
+		}
+	}
+	n, err = t.DeleteRange(compInt(250), compInt(500))
+	c.Check(err, check.Equals, nil)
+	c.Check(n, check.Equals, 1000-len(want))
+	c.Check(t.Len(), check.Equals, len(want))
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Slice(), check.DeepEquals, want)
+
+	n, err = t.DeleteRange(compInt(250), compInt(500))
+	c.Check(n, check.Equals, 0)
+	c.Check(err, check.Equals, nil)
+	n, err = t.DeleteRange(compInt(0), compInt(1000))
+	c.Check(n, check.Equals, len(want))
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Root, check.Equals, (*Node)(nil))
+}
+
+func (s *S) TestDuplicateKeys(c *check.C) {
+	t := &Tree{}
+	c.Check(t.HasDuplicateKeys(), check.Equals, false)
+	c.Check(t.DuplicateKeys(), check.IsNil)
+	for i, iv := range []struct{ s, e compInt }{{0, 2}, {3, 5}, {6, 9}} {
+		t.Insert(&overlap{start: iv.s, end: iv.e, id: uintptr(i)}, false)
+	}
+	c.Check(t.HasDuplicateKeys(), check.Equals, false)
+	c.Check(t.DuplicateKeys(), check.IsNil)
+	for i, iv := range []struct{ s, e compInt }{{3, 4}, {3, 8}, {6, 7}} {
+		t.Insert(&overlap{start: iv.s, end: iv.e, id: uintptr(i + 3)}, false)
+	}
+	c.Check(t.HasDuplicateKeys(), check.Equals, true)
+	c.Check(t.DuplicateKeys(), check.DeepEquals, []Comparable{compInt(3), compInt(6)})
+}
+
+func (s *S) TestHandle(c *check.C) {
+	var (
+		t       = &Tree{}
+		handles []Handle
+	)
+	for i := 0; i < 100; i++ {
+		s := compInt(i % 10)
+		h, err := t.InsertHandle(&overlap{start: s, end: s + 5, id: uintptr(i)}, false)
+		c.Assert(err, check.Equals, nil)
+		handles = append(handles, h)
+	}
+	_, err := t.InsertHandle(&overlap{start: 5, end: 0}, false)
+	c.Check(err, check.Equals, ErrInvertedRange)
+	c.Check(t.Len(), check.Equals, 100)
+	for i, h := range handles {
+		ok, err := t.DeleteHandle(h, false)
+		c.Check(ok, check.Equals, true)
+		c.Check(err, check.Equals, nil)
+		c.Check(t.Len(), check.Equals, 100-i-1)
+		var found bool
+		t.Do(func(e Interface) (done bool) { found = e == h.Elem(); return found })
+		c.Check(found, check.Equals, false)
+		c.Check(t.isBST(), check.Equals, true)
+	}
+	ok, err := t.DeleteHandle(handles[0], false)
+	c.Check(ok, check.Equals, false)
+	c.Check(err, check.Equals, nil)
+	_, err = t.DeleteHandle(Handle{}, false)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+
+	for _, i := range []int{3, 13, 23, 33} {
+		c.Assert(t.InsertShared(handles[i].Elem(), func(a, b Interface) bool {
+			return a.Start().Compare(b.Start()) == 0
+		}, false), check.Equals, nil)
+	}
+	c.Assert(t.Len(), check.Equals, 4)
+	c.Assert(t.Root.Shared, check.HasLen, 3)
+	ok, err = t.DeleteHandle(handles[13], false)
+	c.Check(ok, check.Equals, true)
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Slice(), check.DeepEquals, []Interface{handles[3].Elem(), handles[23].Elem(), handles[33].Elem()})
+}
+
+func (s *S) TestZeroWidth(c *check.C) {
+	for _, fast := range []bool{false, true} {
+		t := &Tree{}
+		var elems []*overlap
+		for i := 0; i < 500; i++ {
+			s := compInt(rand.Intn(100))
+			e := &overlap{start: s, end: s, id: uintptr(i)}
+			if i%2 == 0 {
+				e.end += compInt(rand.Intn(10))
+			}
+			elems = append(elems, e)
+			c.Assert(t.Insert(e, fast), check.Equals, nil)
+		}
+		if fast {
+			t.AdjustRanges()
+		}
+		for s := compInt(-1); s <= 110; s++ {
+			for _, q := range []*overlap{{start: s, end: s}, {start: s, end: s + 1}, {start: s, end: s + 5}} {
+				var n int
+				for _, e := range elems {
+					if q.Overlap(e) {
+						n++
+					}
+				}
+				c.Check(t.Get(q), check.HasLen, n, check.Commentf("query %v", q))
+			}
+		}
+		for i, e := range elems {
+			c.Assert(t.Delete(e, fast), check.Equals, nil)
+			c.Check(t.Len(), check.Equals, len(elems)-i-1, check.Commentf("deleting %v", e))
+			if fast {
+				t.AdjustRanges()
+			}
+		}
+	}
+
+	t := &Tree{}
+	p := &overlap{start: 5, end: 5, id: 0}
+	h, _ := t.InsertHandle(p, false)
+	t.Insert(&overlap{start: 5, end: 7, id: 1}, false)
+	ok, err := t.DeleteHandle(h, false)
+	c.Check(ok, check.Equals, true)
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 1)
+}
+
+func (s *S) TestRangeCount(c *check.C) {
+	t := &Tree{}
+	c.Check(t.RangeCount(compInt(0), compInt(10)), check.Equals, 0)
+	var starts []compInt
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(200))
+		starts = append(starts, s)
+		t.Insert(&overlap{start: s, end: s + 5, id: uintptr(i)}, false)
+	}
+	for lo := compInt(-5); lo < 210; lo += 3 {
+		for hi := lo - 1; hi < lo+50; hi += 7 {
+			var n int
+			for _, s := range starts {
+				if lo <= s && s < hi {
+					n++
+				}
+			}
+			c.Check(t.RangeCount(lo, hi), check.Equals, n, check.Commentf("[%d,%d)", lo, hi))
+		}
+	}
+}
+
+func (s *S) TestTopKByEnd(c *check.C) {
+	t := &Tree{}
+	c.Check(t.TopKByEnd(5), check.IsNil)
+	var ends []int
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		e := s + compInt(rand.Intn(100))
+		ends = append(ends, int(e))
+		t.Insert(&overlap{start: s, end: e, id: uintptr(i)}, false)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ends)))
+	for _, k := range []int{0, 1, 2, 10, 100, 1000, 2000} {
+		got := t.TopKByEnd(k)
+		want := k
+		if want > len(ends) {
+			want = len(ends)
+		}
+		c.Assert(got, check.HasLen, want)
+		for i, e := range got {
+			c.Check(e.End(), check.Equals, compInt(ends[i]), check.Commentf("k=%d i=%d", k, i))
+		}
+	}
+}
+
+func (s *S) TestNearest(c *check.C) {
+	var (
+		t    = &Tree{}
+		dist = func(a, b Comparable) float64 { return math.Abs(float64(a.(compInt) - b.(compInt))) }
+		d    = func(p compInt, e Interface) float64 {
+			switch o := e.(*overlap); {
+			case p < o.start:
+				return float64(o.start - p)
+			case p > o.end:
+				return float64(p - o.end)
+			}
+			return 0
+		}
+	)
+	c.Check(t.Nearest(compInt(0), dist), check.Equals, nil)
+	for i := 0; i < 200; i++ {
+		s := compInt(rand.Intn(2000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(30)), id: uintptr(i)}, false)
+	}
+	for p := compInt(-50); p < 2100; p++ {
+		best := math.Inf(1)
+		t.Do(func(e Interface) (done bool) {
+			best = math.Min(best, d(p, e))
+			return
+		})
+		got := t.Nearest(p, dist)
+		c.Assert(got, check.NotNil)
+		c.Check(d(p, got), check.Equals, best, check.Commentf("p=%d got=%v", p, got))
+	}
+}
+
+func (s *S) TestTrim(c *check.C) {
+	var (
+		t    = &Tree{}
+		clip = func(e Interface, lo, hi Comparable) Interface {
+			o := *e.(*overlap)
+			if o.start < lo.(compInt) {
+				o.start = lo.(compInt)
+			}
+			if o.end > hi.(compInt) {
+				o.end = hi.(compInt)
+			}
+			return &o
+		}
+	)
+	_, err := t.Trim(compInt(10), compInt(0), clip)
+	c.Check(err, check.Equals, ErrInvertedRange)
+	u, err := t.Trim(compInt(0), compInt(10), clip)
+	c.Check(err, check.Equals, nil)
+	c.Check(u.Len(), check.Equals, 0)
+
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(50)), id: uintptr(i)}, false)
+	}
+	lo, hi := compInt(300), compInt(600)
+	u, err = t.Trim(lo, hi, clip)
+	c.Assert(err, check.Equals, nil)
+	c.Check(u.Validate(), check.Equals, nil)
+	want := make(map[uintptr]overlap)
+	t.Do(func(e Interface) (done bool) {
+		o := *e.(*overlap)
+		if o.end >= lo && o.start <= hi {
+			want[o.id] = *clip(&o, lo, hi).(*overlap)
+		}
+		return
+	})
+	c.Check(u.Len(), check.Equals, len(want))
+	u.Do(func(e Interface) (done bool) {
+		o := e.(*overlap)
+		c.Check(*o, check.Equals, want[o.id])
+		c.Check(o.start >= lo && o.end <= hi, check.Equals, true)
+		return
+	})
+	c.Check(t.Len(), check.Equals, 1000)
+
+	_, err = t.Trim(lo, hi, func(e Interface, _, _ Comparable) Interface {
+		return &overlap{start: 1, end: 0, id: e.ID()}
+	})
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+// aliased is an interval type that incorrectly returns itself as its Mutable.
+type aliased struct{ *overlap }
+
+func (a aliased) NewMutable() Mutable { return a.overlap }
+
+func (s *S) TestCheckMutable(c *check.C) {
+	c.Check(CheckMutable(&overlap{start: 1, end: 5}), check.Equals, nil)
+	c.Check(CheckMutable(&overlap{start: 3, end: 3}), check.Equals, nil)
+
+	e := aliased{&overlap{start: 1, end: 5, id: 1}}
+	c.Check(CheckMutable(e), check.Equals, ErrAliasedMutable)
+	c.Check(e.start, check.Equals, compInt(1))
+	c.Check(e.end, check.Equals, compInt(5))
+
+	t := &Tree{}
+	err := t.Insert(e, false)
+	if debug {
+		c.Check(err, check.Equals, ErrAliasedMutable)
+		c.Check(t.Len(), check.Equals, 0)
+	} else {
+		c.Check(err, check.Equals, nil)
+		c.Check(t.Len(), check.Equals, 1)
+	}
+}
+
+func (s *S) TestJoinOverlapping(c *check.C) {
+	type pair struct{ x, y uintptr }
+	var a, b = &Tree{}, &Tree{}
+	c.Check(JoinOverlapping(a, b, func(x, y Interface) (done bool) {
+		c.Errorf("unexpected pair %v %v", x, y)
+		return
+	}), check.Equals, false)
+	for i := 0; i < 300; i++ {
+		s := compInt(rand.Intn(1000))
+		a.Insert(&overlap{start: s, end: s + compInt(rand.Intn(30)), id: uintptr(i)}, false)
+		s = compInt(rand.Intn(1000))
+		b.Insert(&overlap{start: s, end: s + compInt(rand.Intn(30)), id: uintptr(i)}, false)
+	}
+	want := make(map[pair]bool)
+	a.Do(func(x Interface) (done bool) {
+		b.Do(func(y Interface) (done bool) {
+			if x.Overlap(y) {
+				want[pair{x.ID(), y.ID()}] = true
+			}
+			return
+		})
+		return
+	})
+	got := make(map[pair]bool)
+	c.Check(JoinOverlapping(a, b, func(x, y Interface) (done bool) {
+		p := pair{x.ID(), y.ID()}
+		c.Check(got[p], check.Equals, false, check.Commentf("duplicate pair %v %v", x, y))
+		got[p] = true
+		return
+	}), check.Equals, false)
+	c.Check(got, check.DeepEquals, want)
+
+	var n int
+	c.Check(JoinOverlapping(a, b, func(_, _ Interface) (done bool) {
+		n++
+		return n == 5
+	}), check.Equals, true)
+	c.Check(n, check.Equals, 5)
+}
+
+func (s *S) TestDiff(c *check.C) {
+	var (
+		old, new = &Tree{}, &Tree{}
+		equal    = func(a, b Interface) bool { return a.(*overlap).end == b.(*overlap).end }
+		want     = make(map[uintptr]string)
+	)
+	added, removed := Diff(old, new, equal)
+	c.Check(added, check.HasLen, 0)
+	c.Check(removed, check.HasLen, 0)
+
+	for i := 0; i < 500; i++ {
+		s := compInt(rand.Intn(1000))
+		e := &overlap{start: s, end: s + 5, id: uintptr(i)}
+		switch rand.Intn(4) {
+		case 0:
+			old.Insert(e, false)
+			want[e.id] = "removed"
+		case 1:
+			new.Insert(e, false)
+			want[e.id] = "added"
+		case 2:
+			old.Insert(e, false)
+			new.Insert(&overlap{start: s, end: s + 5, id: e.id}, false)
+		case 3:
+			old.Insert(e, false)
+			new.Insert(&overlap{start: s, end: s + 6, id: e.id}, false)
+			want[e.id] = "changed"
+		}
+	}
+
+	got := make(map[uintptr]string)
+	added, removed = Diff(old, new, equal)
+	for _, e := range removed {
+		got[e.(*overlap).id] = "removed"
+	}
+	for _, e := range added {
+		id := e.(*overlap).id
+		if got[id] == "removed" {
+			got[id] = "changed"
+		} else {
+			got[id] = "added"
+		}
+	}
+	c.Check(got, check.DeepEquals, want)
+
+	added, removed = Diff(old, old, equal)
+	c.Check(added, check.HasLen, 0)
+	c.Check(removed, check.HasLen, 0)
+}
+
+func (s *S) TestIsEmpty(c *check.C) {
+	equal := func(a, b Interface) bool { return a.ID() == b.ID() }
+	for _, test := range []struct {
+		name   string
+		delete func(t *Tree, e Interface)
+	}{
+		{"Delete", func(t *Tree, e Interface) { t.Delete(e, false) }},
+		{"DeleteMin", func(t *Tree, _ Interface) { t.DeleteMin(false) }},
+		{"DeleteMax", func(t *Tree, _ Interface) { t.DeleteMax(false) }},
+		{"PopMin", func(t *Tree, _ Interface) { t.PopMin(false) }},
+		{"PopMax", func(t *Tree, _ Interface) { t.PopMax(false) }},
+		{"DeleteElem", func(t *Tree, e Interface) { t.DeleteElem(e, equal, false) }},
+		{"DeleteAll", func(t *Tree, e Interface) { t.DeleteAll(e, false) }},
+		{"DeleteRange", func(t *Tree, e Interface) { t.DeleteRange(e.Start(), e.Start()) }},
+		{"Filter", func(t *Tree, e Interface) { t.Filter(func(o Interface) bool { return o.ID() != e.ID() }) }},
+	} {
+		for _, shared := range []bool{false, true} {
+			t := &Tree{}
+			c.Check(t.IsEmpty(), check.Equals, true)
+			var elems []Interface
+			for i := 0; i < 100; i++ {
+				s := compInt(rand.Intn(50))
+				e := &overlap{start: s, end: s + 1, id: uintptr(i)}
+				elems = append(elems, e)
+				if shared {
+					t.InsertShared(e, func(a, b Interface) bool {
+						return a.Start().Compare(b.Start()) == 0 && a.End().Compare(b.End()) == 0
+					}, false)
+				} else {
+					t.Insert(e, false)
+				}
+			}
+			for _, e := range elems {
+				if t.IsEmpty() {
+					break
+				}
+				test.delete(t, e)
+				c.Check(t.IsEmpty(), check.Equals, t.Len() == 0, check.Commentf("%s shared=%t", test.name, shared))
+				c.Check(t.Len(), check.Equals, len(t.Slice()), check.Commentf("%s shared=%t", test.name, shared))
+			}
+			c.Check(t.IsEmpty(), check.Equals, true, check.Commentf("%s shared=%t", test.name, shared))
+			c.Check(t.Len(), check.Equals, 0, check.Commentf("%s shared=%t", test.name, shared))
+		}
+	}
+}
+
+func (s *S) TestKNearest(c *check.C) {
+	var (
+		t    = &Tree{}
+		dist = func(p Comparable, e Interface) float64 {
+			switch o, p := e.(*overlap), p.(compInt); {
+			case p < o.start:
+				return float64(o.start - p)
+			case p > o.end:
+				return float64(p - o.end)
+			}
+			return 0
+		}
+	)
+	c.Check(t.KNearest(compInt(0), 3, dist), check.IsNil)
+	for i := 0; i < 300; i++ {
+		s := compInt(rand.Intn(2000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(30)), id: uintptr(i)}, false)
+	}
+	for p := compInt(-50); p < 2100; p += 3 {
+		var all []float64
+		t.Do(func(e Interface) (done bool) { all = append(all, dist(p, e)); return })
+		sort.Float64s(all)
+		for _, k := range []int{0, 1, 5, 20, 400} {
+			got := t.KNearest(p, k, dist)
+			want := k
+			if want > len(all) {
+				want = len(all)
+			}
+			c.Assert(got, check.HasLen, want)
+			seen := make(map[Interface]bool)
+			for i, e := range got {
+				c.Check(dist(p, e), check.Equals, all[i], check.Commentf("p=%d k=%d i=%d", p, k, i))
+				c.Check(seen[e], check.Equals, false)
+				seen[e] = true
+			}
+		}
+		if n := t.Nearest(p, func(a, b Comparable) float64 {
+			return math.Abs(float64(a.(compInt) - b.(compInt)))
+		}); n != nil {
+			c.Check(dist(p, n), check.Equals, all[0])
+		}
+	}
+}
+
+func (s *S) TestDeterministicOrder(c *check.C) {
+	var elems []*overlap
+	for i, id := range rand.Perm(200) {
+		s := compInt(i % 10)
+		elems = append(elems, &overlap{start: s, end: s + compInt(rand.Intn(10)), id: uintptr(id)})
+	}
+	var want []Interface
+	for trial := 0; trial < 5; trial++ {
+		t := &Tree{}
+		for _, i := range rand.Perm(len(elems)) {
+			t.Insert(elems[i], false)
+		}
+		got := t.Slice()
+		c.Check(sort.IsSorted(byKey(got)), check.Equals, true)
+		if want == nil {
+			want = got
+		} else {
+			c.Check(got, check.DeepEquals, want)
+		}
+		for _, e := range elems {
+			f, err := t.Floor(e)
+			c.Check(err, check.Equals, nil)
+			c.Check(f, check.Equals, e)
+			f, err = t.Ceil(e)
+			c.Check(err, check.Equals, nil)
+			c.Check(f, check.Equals, e)
+		}
+		for _, i := range rand.Perm(len(elems)) {
+			c.Check(t.Delete(elems[i], false), check.Equals, nil)
+		}
+		c.Check(t.Len(), check.Equals, 0)
+	}
+}
+
+func (s *S) TestNoEmptyNodes(c *check.C) {
+	for _, fast := range []bool{false, true} {
+		t := &Tree{}
+		for i := 0; i < 5000; i++ {
+			s := compInt(rand.Intn(500))
+			e := &overlap{start: s, end: s + 1, id: uintptr(rand.Intn(1000))}
+			switch rand.Intn(4) {
+			case 0:
+				t.Delete(e, fast)
+			case 1:
+				if rand.Intn(2) == 0 {
+					t.DeleteMin(fast)
+				} else {
+					t.DeleteMax(fast)
+				}
+			default:
+				t.Insert(e, fast)
+			}
+			if i%100 != 0 {
+				continue
+			}
+			var n int
+			t.DoWithMeta(func(e Interface, _ int, _ llrb.Color) (done bool) {
+				c.Assert(e, check.NotNil)
+				n++
+				return
+			})
+			c.Check(n, check.Equals, t.nodes())
+			c.Check(n, check.Equals, t.Len())
+		}
+	}
+}
+
+func (s *S) TestDeleteAbsentSharedID(c *check.C) {
+	t := &Tree{}
+	for i := compInt(0); i < 20; i++ {
+		t.Insert(&overlap{start: i * 10, end: i*10 + 5, id: 1}, false)
+	}
+	for i := compInt(0); i < 20; i++ {
+		c.Check(t.Delete(&overlap{start: i*10 + 1, end: i*10 + 2, id: 1}, false), check.Equals, nil)
+		c.Check(t.Len(), check.Equals, 20)
+		c.Check(t.Validate(), check.Equals, nil)
+	}
+	for i := compInt(0); i < 20; i++ {
+		c.Check(t.Delete(&overlap{start: i * 10, end: i*10 + 5, id: 1}, false), check.Equals, nil)
+		c.Check(t.Len(), check.Equals, int(19-i))
+	}
+}
+
+func (s *S) TestGetMulti(c *check.C) {
+	t := &Tree{}
+	got, err := t.GetMulti([]Overlapper{&overlap{start: 0, end: 10}})
+	c.Check(got, check.IsNil)
+	c.Check(err, check.Equals, nil)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(20)), id: uintptr(i)}, false)
+	}
+	for trial := 0; trial < 20; trial++ {
+		var qs []Overlapper
+		for i := rand.Intn(10); i >= 0; i-- {
+			s := compInt(rand.Intn(1000))
+			qs = append(qs, &overlap{start: s, end: s + compInt(rand.Intn(50))})
+		}
+		var want []Interface
+		t.Do(func(e Interface) (done bool) {
+			for _, q := range qs {
+				if q.Overlap(e) {
+					want = append(want, e)
+					break
+				}
+			}
+			return
+		})
+		got, err := t.GetMulti(qs)
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.DeepEquals, want)
+	}
+
+	_, err = t.GetMulti([]Overlapper{&overlap{start: 0, end: 1}, nil})
+	c.Check(err, check.DeepEquals, &BatchError{Index: 1, Err: ErrNilOverlapper})
+	_, err = t.GetMulti([]Overlapper{&overlap{start: 5, end: 1}})
+	c.Check(err, check.DeepEquals, &BatchError{Index: 0, Err: ErrInvertedRange})
+}
+
+func (s *S) TestInsertN(c *check.C) {
+	t := &Tree{}
+	d, err := t.InsertN(&overlap{start: 0, end: 5, id: 0}, false)
+	c.Check(d, check.Equals, 1)
+	c.Check(err, check.Equals, nil)
+	d, err = t.InsertN(&overlap{start: 0, end: 5, id: 1}, false)
+	c.Check(d, check.Equals, 1)
+	c.Check(err, check.Equals, nil)
+	d, err = t.InsertN(&overlap{start: 0, end: 7, id: 1}, false)
+	c.Check(d, check.Equals, 0)
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 2)
+	d, err = t.InsertN(&overlap{start: 5, end: 0, id: 2}, false)
+	c.Check(d, check.Equals, 0)
+	c.Check(err, check.Equals, ErrInvertedRange)
+	d, err = t.InsertN(nil, false)
+	c.Check(d, check.Equals, 0)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	c.Check(t.Len(), check.Equals, 2)
+}
+
+func (s *S) TestCountExact(c *check.C) {
+	var (
+		t     = &Tree{}
+		ref   = make(map[[2]int]bool)
+		equal = func(a, b Interface) bool {
+			return a.Start().Compare(b.Start()) == 0 && a.End().Compare(b.End()) == 0
+		}
+	)
+	for i := 0; i < 20000; i++ {
+		// Shared intervals take IDs distinct from those inserted
+		// into nodes since Insert does not search shared intervals.
+		op := rand.Intn(8)
+		s := compInt(rand.Intn(200))
+		e := &overlap{start: s, end: s + 3, id: uintptr(rand.Intn(25))}
+		if op == 3 || rand.Intn(2) == 0 {
+			e.id += 25
+		}
+		key := [2]int{int(s), int(e.id)}
+		switch op {
+		case 0, 1, 2:
+			if e.id >= 25 {
+				continue
+			}
+			d, _ := t.InsertN(e, false)
+			c.Check(d == 1, check.Equals, !ref[key])
+			ref[key] = true
+		case 3:
+			if !ref[key] {
+				t.InsertShared(e, equal, false)
+				ref[key] = true
+			}
+		case 4, 5:
+			ok, _ := t.DeleteHandle(Handle{e: e}, false)
+			c.Check(ok, check.Equals, ref[key])
+			delete(ref, key)
+		case 6:
+			if e, ok := t.PopMin(false); ok {
+				delete(ref, [2]int{int(e.Start().(compInt)), int(e.ID())})
+			}
+		case 7:
+			if e, ok := t.PopMax(false); ok {
+				delete(ref, [2]int{int(e.Start().(compInt)), int(e.ID())})
+			}
+		}
+		c.Assert(t.Len(), check.Equals, len(ref), check.Commentf("step %d", i))
+	}
+	c.Check(t.Validate(), check.Equals, nil)
+}
+
+func (s *S) TestEstimateOverlaps(c *check.C) {
+	t := &Tree{}
+	c.Check(t.EstimateOverlaps(&overlap{start: 0, end: 10}), check.Equals, 0)
+	c.Check(t.EstimateOverlaps(nil), check.Equals, 0)
+
+	for i := 0; i < 20; i++ {
+		s := compInt(rand.Intn(100))
+		t.Insert(&overlap{start: s, end: s + 5, id: uintptr(i)}, false)
+	}
+	for _, q := range []*overlap{{start: 0, end: 200}, {start: 10, end: 20}, {start: 300, end: 400}} {
+		c.Check(t.EstimateOverlaps(q), check.Equals, len(t.Get(q)))
+	}
+
+	t = &Tree{}
+	for i := 0; i < 20000; i++ {
+		s := compInt(rand.Intn(20000))
+		t.Insert(&overlap{start: s, end: s + 10, id: uintptr(i)}, false)
+	}
+	c.Check(t.EstimateOverlaps(&overlap{start: -1, end: 40000}), check.Equals, t.Len())
+	for _, q := range []*overlap{{start: 1000, end: 9000}, {start: 10000, end: 10500}} {
+		got, want := float64(t.EstimateOverlaps(q)), float64(len(t.Get(q)))
+		c.Check(got > want/2 && got < want*2, check.Equals, true, check.Commentf("estimate %v for %d overlaps", got, int(want)))
+	}
+}
+
+func (s *S) TestDeleteAll(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	c.Check(t.DeleteAll(&overlap{start: 0, end: 1}, false), check.Equals, 0)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	for _, q := range []*overlap{
+		{start: 100, end: 200},
+		{start: 500, end: 501},
+		{start: -10, end: 10},
+		{start: 2000, end: 3000},
+		{start: 300, end: 700},
+	} {
+		n := len(t.Get(q))
+		l := t.Len()
+		c.Check(t.DeleteAll(q, false), check.Equals, n)
+		c.Check(t.Len(), check.Equals, l-n)
+		c.Check(t.Get(q), check.HasLen, 0)
+		c.Check(t.isBST(), check.Equals, true)
+		c.Check(t.is23_234(), check.Equals, true)
+		c.Check(t.isBalanced(), check.Equals, true)
+		c.Check(t.isRanged(), check.Equals, true)
+		c.Check(t.isSized(), check.Equals, true)
+	}
+	t.DeleteAll(&overlap{start: -length, end: compInt(max) + length}, false)
+	c.Check(*t, check.Equals, Tree{})
+}
+
+func (s *S) TestPartitions(c *check.C) {
+	t := &Tree{}
+	c.Check(t.Partitions(4), check.IsNil)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + 5, id: uintptr(i)}, false)
+	}
+	elems := t.Slice()
+	c.Check(t.Partitions(1), check.IsNil)
+	for _, n := range []int{2, 3, 7, 10, 999, 1000, 5000} {
+		parts := t.Partitions(n)
+		want := n - 1
+		if want >= len(elems) {
+			want = len(elems) - 1
+		}
+		c.Assert(parts, check.HasLen, want)
+		last := 0
+		for _, e := range parts {
+			r := t.Rank(e)
+			c.Check(elems[r], check.Equals, e)
+			size := r - last
+			min, max := len(elems)/(want+1), (len(elems)+want)/(want+1)
+			c.Check(size >= min && size <= max, check.Equals, true, check.Commentf("n=%d size=%d", n, size))
+			last = r
+		}
+	}
+}
+
+func (s *S) TestMerge(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t, u       = &Tree{}, &Tree{}
+		length     = compInt(10)
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		e := &overlap{start: s, end: s + length, id: uintptr(i)}
+		if i&1 == 0 {
+			t.Insert(e, false)
+		} else {
+			u.Insert(e, false)
+		}
+	}
+	var want []Interface
+	t.Do(func(e Interface) (done bool) { want = append(want, e); return })
+	u.Do(func(e Interface) (done bool) { want = append(want, e); return })
+
+	t.Merge(u, false)
+	c.Check(t.Len(), check.Equals, count)
+	c.Check(u.Len(), check.Equals, count/2)
+	c.Check(t.Validate(), check.Equals, nil)
+	for _, e := range want {
+		c.Check(t.Get(e), check.Not(check.HasLen), 0)
+	}
+
+	t.Merge(t, false)
+	c.Check(t.Len(), check.Equals, count)
+	t.Merge(&Tree{}, false)
+	c.Check(t.Len(), check.Equals, count)
+}
+
+func (s *S) TestCoalesce(c *check.C) {
+	t := &Tree{}
+	join := func(a, b Interface) Interface {
+		ai, bi := a.(*overlap), b.(*overlap)
+		end := ai.end
+		if bi.end > end {
+			end = bi.end
+		}
+		return &overlap{start: ai.start, end: end, id: ai.id}
+	}
+	c.Check(t.Coalesce(join), check.Equals, 0)
+	for i, iv := range []*overlap{
+		{start: 0, end: 2},
+		{start: 2, end: 4},
+		{start: 1, end: 6},
+		{start: 3, end: 4},
+		{start: 1, end: 3},
+		{start: 4, end: 6},
+		{start: 7, end: 8},
+		{start: 9, end: 12},
+		{start: 10, end: 11},
+		{start: 14, end: 15},
+	} {
+		iv.id = uintptr(i)
+		t.Insert(iv, false)
+	}
+	c.Check(t.Coalesce(join), check.Equals, 6)
+	c.Check(t.Len(), check.Equals, 4)
+	c.Check(t.Validate(), check.Equals, nil)
+	var got []string
+	t.Do(func(e Interface) (done bool) { got = append(got, e.(*overlap).String()); return })
+	c.Check(got, check.DeepEquals, []string{"[0,6)", "[7,8)", "[9,12)", "[14,15)"})
+	c.Check(t.Coalesce(join), check.Equals, 0)
+}
+
+func (s *S) TestGetInto(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+		buf        []Interface
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	for s := compInt(-length); s <= compInt(max)+length; s++ {
+		q := &overlap{start: s, end: s + 1}
+		buf = t.GetInto(q, buf[:0])
+		want := t.Get(q)
+		if len(want) == 0 {
+			c.Check(buf, check.HasLen, 0)
+		} else {
+			c.Check(buf, check.DeepEquals, want)
+		}
+	}
+
+	head := &overlap{start: -1, end: 0}
+	q := &overlap{start: 0, end: compInt(max)}
+	o := t.GetInto(q, []Interface{head})
+	c.Check(o[0], check.Equals, Interface(head))
+	c.Check(o[1:], check.DeepEquals, t.Get(q))
+}
+
+// countOverlaps returns the result of t.CountOverlaps, failing c if an error is returned.
+func countOverlaps(c *check.C, t *Tree, q Overlapper) int {
+	n, err := t.CountOverlaps(q)
+	c.Assert(err, check.Equals, nil)
+	return n
+}
+
+func (s *S) TestCountOverlaps(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	c.Check(countOverlaps(c, t, &overlap{start: 0, end: 1}), check.Equals, 0)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	for s := compInt(-length); s <= compInt(max)+length; s++ {
+		q := &overlap{start: s, end: s + length/2}
+		c.Check(countOverlaps(c, t, q), check.Equals, len(t.Get(q)))
+	}
+	c.Check(countOverlaps(c, t, &overlap{start: -length, end: compInt(max) + length}), check.Equals, count)
+	_, err := t.CountOverlaps(nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, err = t.CountOverlaps(&overlap{start: 10, end: 5})
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestFloor(c *check.C) {
+	min, max := compInt(0), compInt(1000)
+	t := &Tree{}
+	for i := min; i <= max; i++ {
+		if i&1 == 0 { // Insert even numbers only.
+			t.Insert(&overlap{start: i, end: i + 1}, false)
+		}
+	}
+	for i := min; i <= max; i++ {
+		l, _ := t.Floor(&overlap{start: i, end: i + 1})
+		if i&1 == 0 {
+			c.Check(l, check.DeepEquals, &overlap{start: i, end: i + 1}) // Check even Floors are themselves.
+		} else {
+			c.Check(l, check.DeepEquals, &overlap{start: i - 1, end: i}) // Check odd Floors are the previous number.
+		}
+	}
+	l, _ := t.Floor(&overlap{start: min - 1, end: min})
+	c.Check(l, check.DeepEquals, Interface(nil))
+}
+
+func (s *S) TestCeil(c *check.C) {
+	min, max := compInt(0), compInt(1000)
+	t := &Tree{}
+	for i := min; i <= max; i++ {
+		if i&1 == 1 { // Insert odd numbers only.
+			t.Insert(&overlap{start: i, end: i + 1}, false)
+		}
+	}
+	for i := min; i < max; i++ {
+		u, _ := t.Ceil(&overlap{start: i, end: i + 1})
+		if i&1 == 1 {
+			c.Check(u, check.DeepEquals, &overlap{start: i, end: i + 1}) // Check odd Ceils are themselves.
+		} else {
+			c.Check(u, check.DeepEquals, &overlap{start: i + 1, end: i + 2}) // Check even Ceils are the next number.
+		}
+	}
+	u, _ := t.Ceil(&overlap{start: max, end: max + 2})
+	c.Check(u, check.DeepEquals, Comparable(nil))
+}
+
+func (s *S) TestFloorCeilWhere(c *check.C) {
+	min, max := compInt(0), compInt(1000)
+	t := &Tree{}
+	_, err := t.CeilWhere(nil, nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	_, err = t.FloorWhere(nil, nil)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	for i := min; i < max; i++ {
+		t.Insert(&overlap{start: i, end: i + 1}, false)
+	}
+	tens := func(e Interface) bool { return e.Start().(compInt)%10 == 0 }
+	for i := min - 5; i < max+5; i++ {
+		q := &overlap{start: i, end: i + 1}
+		var want Interface
+		if ceil := (i + 9) / 10 * 10; i <= 0 {
+			want = &overlap{start: 0, end: 1}
+		} else if ceil < max {
+			want = &overlap{start: ceil, end: ceil + 1}
+		}
+		u, err := t.CeilWhere(q, tens)
+		c.Check(err, check.Equals, nil)
+		c.Check(u, check.DeepEquals, want, check.Commentf("ceil of %d", i))
+
+		want = nil
+		if floor := i / 10 * 10; i >= max {
+			want = &overlap{start: max - 10, end: max - 9}
+		} else if i >= 0 {
+			want = &overlap{start: floor, end: floor + 1}
+		}
+		l, err := t.FloorWhere(q, tens)
+		c.Check(err, check.Equals, nil)
+		c.Check(l, check.DeepEquals, want, check.Commentf("floor of %d", i))
+	}
+	u, _ := t.CeilWhere(&overlap{start: 1, end: 2}, func(Interface) bool { return false })
+	c.Check(u, check.Equals, nil)
+}
+
+func (s *S) TestDoRange(c *check.C) {
+	var (
+		count, max = 1000, 100
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	done, err := t.DoRange(func(Interface) (done bool) { return true }, compInt(0), compInt(1))
+	c.Check(done, check.Equals, false)
+	c.Check(err, check.Equals, nil)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	for _, r := range []struct{ from, to compInt }{
+		{0, 0}, {0, 1}, {-10, 10}, {10, 50}, {50, 51}, {90, 200}, {0, compInt(max)},
+	} {
+		var want, got []Interface
+		t.Do(func(e Interface) (done bool) {
+			if s := e.Start().(compInt); s >= r.from && s < r.to {
+				want = append(want, e)
+			}
+			return
+		})
+		_, err := t.DoRange(func(e Interface) (done bool) { got = append(got, e); return }, r.from, r.to)
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.DeepEquals, want)
+	}
+	for _, r := range []struct{ lo, hi compInt }{
+		{0, 0}, {0, 1}, {-10, 10}, {10, 50}, {50, 50}, {90, 200}, {0, compInt(max)},
+	} {
+		var want, got []Interface
+		t.Do(func(e Interface) (done bool) {
+			if s := e.Start().(compInt); s >= r.lo && s <= r.hi {
+				want = append(want, e)
+			}
+			return
+		})
+		_, err := t.DoRangeInclusive(func(e Interface) (done bool) { got = append(got, e); return }, r.lo, r.hi)
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.DeepEquals, want, check.Commentf("[%d, %d]", r.lo, r.hi))
+	}
+	_, err = t.DoRangeInclusive(func(Interface) (done bool) { c.Error("unexpected call"); return }, compInt(1), compInt(0))
+	c.Check(err, check.Equals, ErrInvertedRange)
+
+	var n int
+	done, err = t.DoRange(func(Interface) (done bool) { n++; return n == 5 }, compInt(0), compInt(max))
+	c.Check(done, check.Equals, true)
+	c.Check(err, check.Equals, nil)
+	c.Check(n, check.Equals, 5)
+	_, err = t.DoRange(func(Interface) (done bool) { c.Error("unexpected call"); return }, compInt(1), compInt(0))
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestFloorCeilPoint(c *check.C) {
+	min, max := compInt(0), compInt(1000)
+	t := &Tree{}
+	l, _ := t.FloorPoint(min)
+	c.Check(l, check.Equals, nil)
+	u, _ := t.CeilPoint(min)
+	c.Check(u, check.Equals, nil)
+	for i := min; i <= max; i++ {
+		if i&1 == 0 { // Insert even numbers only, twice.
+			t.Insert(&overlap{start: i, end: i + 1, id: uintptr(2 * i)}, false)
+			t.Insert(&overlap{start: i, end: i + 2, id: uintptr(2*i + 1)}, false)
+		}
+	}
+	for i := min; i <= max; i++ {
+		l, _ := t.FloorPoint(i)
+		u, _ := t.CeilPoint(i)
+		if i&1 == 0 {
+			c.Check(l, check.DeepEquals, &overlap{start: i, end: i + 2, id: uintptr(2*i + 1)})
+			c.Check(u, check.DeepEquals, &overlap{start: i, end: i + 1, id: uintptr(2 * i)})
+		} else {
+			c.Check(l, check.DeepEquals, &overlap{start: i - 1, end: i + 1, id: uintptr(2*(i-1) + 1)})
+			if i < max {
+				c.Check(u, check.DeepEquals, &overlap{start: i + 1, end: i + 2, id: uintptr(2 * (i + 1))})
+			}
+		}
+	}
+	l, _ = t.FloorPoint(min - 1)
+	c.Check(l, check.Equals, nil)
+	u, _ = t.CeilPoint(max + 1)
+	c.Check(u, check.Equals, nil)
+}
+
+func (s *S) TestPredecessorSuccessor(c *check.C) {
+	var (
+		count, max = 1000, 100
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	p, _ := t.Predecessor(&overlap{start: 0})
+	c.Check(p, check.Equals, nil)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	var elems []Interface
+	t.Do(func(e Interface) (done bool) { elems = append(elems, e); return })
+	for i, e := range elems {
+		p, _ := t.Predecessor(e)
+		s, _ := t.Successor(e)
+		if i == 0 {
+			c.Check(p, check.Equals, nil)
+		} else {
+			c.Check(p, check.Equals, elems[i-1])
+		}
+		if i == len(elems)-1 {
+			c.Check(s, check.Equals, nil)
+		} else {
+			c.Check(s, check.Equals, elems[i+1])
+		}
+	}
+	n, _ := t.Successor(&overlap{start: -1})
+	c.Check(n, check.Equals, elems[0])
+	p, _ = t.Predecessor(&overlap{start: compInt(max)})
+	c.Check(p, check.Equals, elems[len(elems)-1])
+}
+
+func (s *S) TestRandomlyInsertedGet(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(100)
+		verify     = map[overlap]struct{}{}
+		verified   = map[overlap]struct{}{}
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		v := overlap{start: s, end: s + length}
+		t.Insert(&v, false)
+		verify[v] = struct{}{}
+	}
+	// Random fetch order.
+	for v := range verify {
+		o := t.Get(&v)
+		c.Check(len(o), check.Not(check.Equals), 0) // Check inserted elements are present.
+		for _, iv := range o {
+			vr := *iv.(*overlap)
+			_, ok := verify[vr]
+			c.Check(ok, check.Equals, true, check.Commentf("%v should exist", vr))
+			if ok {
+				verified[vr] = struct{}{}
+			}
+		}
+	}
+	c.Check(len(verify), check.Equals, len(verified))
+	for v := range verify {
+		_, ok := verified[v]
+		c.Check(ok, check.Equals, true, check.Commentf("%v should exist", v))
+	}
+
+	// Check all possible insertions.
+	for s := compInt(0); s <= compInt(max); s++ {
+		v := overlap{start: s, end: s + length}
+		o := t.Get(&v)
+		if _, ok := verify[v]; ok {
+			c.Check(len(o), check.Not(check.Equals), 0) // Check inserted elements are present.
+		}
+	}
+}
+
+func (s *S) TestRandomInsertion(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(100)
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		v := overlap{start: s, end: s + length}
+		t.Insert(&v, false)
+		failed := false
+		failed = failed || !c.Check(t.isBST(), check.Equals, true)
+		failed = failed || !c.Check(t.is23_234(), check.Equals, true)
+		failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
+		failed = failed || !c.Check(t.isRanged(), check.Equals, true)
+		failed = failed || !c.Check(t.isSized(), check.Equals, true)
+		if failed {
+			if *printTree {
+				c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
+			}
+			if *genDot && t.Len() <= *dotLimit {
+				err := t.dotFile(fmt.Sprintf("TestRandomInsertion_after_ins_%d_%d", v.start, v.end), "")
+				if err != nil {
+					c.Errorf("Dot file write failed: %v", err)
+				}
+			}
+			c.Fatal("Cannot continue test: invariant contradiction")
+		}
+	}
+}
+
+func (s *S) TestRandomDeletion(c *check.C) {
+	var (
+		count, max = 14, 3
+		r          = make([]overlap, count)
+		t          = &Tree{}
+		length     = compInt(1)
+	)
+	for i := range r {
+		s := compInt(rand.Intn(max))
+		r[i] = overlap{start: s, end: s + length, id: uintptr(i)}
+		t.Insert(&r[i], false)
+	}
+	for i, v := range r {
+		var dotString string
+		if *genDot && t.Len() <= *dotLimit {
+			dotString = t.dot(fmt.Sprintf("TestRandomDeletion_before_del_%d_%d_%d", i, v.start, v.end))
+		}
+		t.Delete(&v, false)
+		if t != nil {
+			failed := false
+			failed = failed || !c.Check(t.isBST(), check.Equals, true)
+			failed = failed || !c.Check(t.is23_234(), check.Equals, true)
+			failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
+			failed = failed || !c.Check(t.isRanged(), check.Equals, true)
+			failed = failed || !c.Check(t.isSized(), check.Equals, true)
+			if failed {
+				if *printTree {
+					c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
+				}
+				if *genDot && t.Len() <= *dotLimit {
+					var err error
+					err = (*Tree)(nil).dotFile(fmt.Sprintf("TestRandomDeletion_before_del_%d_%d_%d", i, v.start, v.end), dotString)
+					if err != nil {
+						c.Errorf("Dot file write failed: %v", err)
+					}
+					err = t.dotFile(fmt.Sprintf("TestRandomDeletion_after_del_%d_%d_%d", i, v.start, v.end), "")
+					if err != nil {
+						c.Errorf("Dot file write failed: %v", err)
+					}
+				}
+				c.Fatal("Cannot continue test: invariant contradiction")
+			}
+		}
+	}
+	c.Check(*t, check.DeepEquals, Tree{})
+}
+
+func (s *S) TestDeleteMinMax(c *check.C) {
+	var (
+		min, max = compInt(0), compInt(10)
+		t        = &Tree{}
+		length   = compInt(1)
+		dI       int
+	)
+	for i := min; i <= max; i++ {
+		v := overlap{start: i, end: i + length}
+		t.Insert(&v, false)
+		dI = t.Len()
+	}
+	c.Check(dI, check.Equals, int(max-min+1))
+	for i, m := 0, int(max); i < m/2; i++ {
 		var failed bool
 		t.DeleteMin(false)
 		dI--
@@ -698,6 +3088,7 @@ func (s *S) TestDeleteMinMax(c *check.C) {
 		failed = failed || !c.Check(t.is23_234(), check.Equals, true)
 		failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
 		failed = failed || !c.Check(t.isRanged(), check.Equals, true)
+		failed = failed || !c.Check(t.isSized(), check.Equals, true)
 		if failed {
 			if *printTree {
 				c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
@@ -719,6 +3110,7 @@ func (s *S) TestDeleteMinMax(c *check.C) {
 		failed = failed || !c.Check(t.is23_234(), check.Equals, true)
 		failed = failed || !c.Check(t.isBalanced(), check.Equals, true)
 		failed = failed || !c.Check(t.isRanged(), check.Equals, true)
+		failed = failed || !c.Check(t.isSized(), check.Equals, true)
 		if failed {
 			if *printTree {
 				c.Logf("Failing tree: %s\n\n", t.Root.describeTree(false, true))
@@ -787,6 +3179,180 @@ func (s *S) TestIssue15(c *check.C) {
 	c.Check(len(got), check.Equals, 1, check.Commentf("Expected one overlap, got %d", len(got)))
 }
 
+func (s *S) TestClone(c *check.C) {
+	var (
+		min, max = compInt(0), compInt(1000)
+		t        = &Tree{}
+		length   = compInt(10)
+	)
+	for i := min; i <= max; i++ {
+		t.Insert(&overlap{start: i, end: i + length, id: uintptr(i)}, false)
+	}
+	ct := t.Clone()
+	c.Check(ct, check.DeepEquals, t)
+
+	var shared func(a, b *Node) bool
+	shared = func(a, b *Node) bool {
+		if a == nil || b == nil {
+			return false
+		}
+		return a == b || a.Range == b.Range || shared(a.Left, b.Left) || shared(a.Right, b.Right)
+	}
+	c.Check(shared(t.Root, ct.Root), check.Equals, false)
+
+	for i := min; i <= max; i += 2 {
+		ct.Delete(&overlap{start: i, end: i + length, id: uintptr(i)}, false)
+	}
+	c.Check(ct.Len(), check.Equals, int(max-min)/2)
+	c.Check(ct.isBST(), check.Equals, true)
+	c.Check(ct.is23_234(), check.Equals, true)
+	c.Check(ct.isBalanced(), check.Equals, true)
+	c.Check(ct.isRanged(), check.Equals, true)
+
+	c.Check(t.Len(), check.Equals, int(max-min)+1)
+	c.Check(t.isBST(), check.Equals, true)
+	c.Check(t.is23_234(), check.Equals, true)
+	c.Check(t.isBalanced(), check.Equals, true)
+	c.Check(t.isRanged(), check.Equals, true)
+	for i := min; i <= max; i++ {
+		c.Check(len(t.Get(&overlap{start: i, end: i + 1})) > 0, check.Equals, true)
+	}
+
+	c.Check((&Tree{}).Clone(), check.DeepEquals, &Tree{})
+}
+
+func (s *S) TestAnyOverlap(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	o, ok, err := t.AnyOverlap(&overlap{start: 0, end: 1})
+	c.Check(o, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	c.Check(err, check.Equals, nil)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+	}
+	for s := compInt(-length); s <= compInt(max)+length; s++ {
+		q := &overlap{start: s, end: s + 1}
+		all := t.Get(q)
+		o, ok, err := t.AnyOverlap(q)
+		c.Check(err, check.Equals, nil)
+		c.Check(ok, check.Equals, len(all) != 0)
+		if !ok {
+			c.Check(o, check.Equals, nil)
+			continue
+		}
+		c.Check(q.Overlap(o.(*overlap)), check.Equals, true)
+		var found bool
+		for _, e := range all {
+			if e == o {
+				found = true
+				break
+			}
+		}
+		c.Check(found, check.Equals, true, check.Commentf("%v not in %v", o, all))
+	}
+	o, ok, err = t.AnyOverlap(nil)
+	c.Check(o, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+	o, ok, err = t.AnyOverlap(&overlap{start: 10, end: 5})
+	c.Check(o, check.Equals, nil)
+	c.Check(ok, check.Equals, false)
+	c.Check(err, check.Equals, ErrInvertedRange)
+}
+
+func (s *S) TestReplace(c *check.C) {
+	var (
+		min, max = compInt(0), compInt(100)
+		t        = &Tree{}
+		length   = compInt(10)
+	)
+	for i := min; i <= max; i++ {
+		t.Insert(&overlap{start: i / 2, end: i/2 + length, id: uintptr(i)}, false)
+	}
+	shape := t.Root.describeTree(false, true)
+	for i := min; i <= max; i++ {
+		old := t.Get(&overlap{start: i / 2, end: i/2 + 1})
+		var o Interface
+		for _, e := range old {
+			if e.ID() == uintptr(i) {
+				o = e
+			}
+		}
+		c.Assert(o, check.Not(check.Equals), nil)
+		new := &overlap{start: i / 2, end: i/2 + length, id: uintptr(i)}
+		c.Check(t.Replace(o, new), check.Equals, nil)
+		var found bool
+		for _, e := range t.Get(&overlap{start: i / 2, end: i/2 + 1}) {
+			if e == Interface(new) {
+				found = true
+			}
+			c.Check(e, check.Not(check.Equals), o)
+		}
+		c.Check(found, check.Equals, true)
+	}
+	c.Check(t.Root.describeTree(false, true), check.Equals, shape)
+	c.Check(t.Len(), check.Equals, int(max-min)+1)
+
+	c.Check(t.Replace(
+		&overlap{start: 0, end: length, id: 0},
+		&overlap{start: 0, end: length + 1, id: 0},
+	), check.Equals, ErrMismatchedKey)
+	c.Check(t.Replace(
+		&overlap{start: 0, end: length, id: 0},
+		&overlap{start: 0, end: length, id: 1},
+	), check.Equals, ErrMismatchedKey)
+	c.Check(t.Replace(
+		&overlap{start: 0, end: length, id: 2},
+		&overlap{start: 0, end: length, id: 2},
+	), check.Equals, ErrNotFound)
+}
+
+func (s *S) TestSelectRank(c *check.C) {
+	var (
+		count, max = 1000, 100
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	_, err := t.Select(0)
+	c.Check(err, check.Equals, ErrOutOfRange)
+	c.Check(t.Rank(&overlap{start: 0, end: 1}), check.Equals, 0)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+		c.Assert(t.isSized(), check.Equals, true)
+	}
+	var elems []Interface
+	t.Do(func(e Interface) (done bool) { elems = append(elems, e); return })
+	for k, e := range elems {
+		o, err := t.Select(k)
+		c.Check(err, check.Equals, nil)
+		c.Check(o, check.Equals, e)
+		c.Check(t.Rank(e), check.Equals, k)
+	}
+	_, err = t.Select(-1)
+	c.Check(err, check.Equals, ErrOutOfRange)
+	_, err = t.Select(count)
+	c.Check(err, check.Equals, ErrOutOfRange)
+	c.Check(t.Rank(&overlap{start: -1, end: 0}), check.Equals, 0)
+	c.Check(t.Rank(&overlap{start: compInt(max), end: compInt(max) + 1}), check.Equals, count)
+
+	for i := 0; i < count/2; i++ {
+		t.Delete(elems[i*2], false)
+		c.Assert(t.isSized(), check.Equals, true)
+		if i%2 == 0 {
+			t.DeleteMin(false)
+		} else {
+			t.DeleteMax(true)
+		}
+		c.Assert(t.isSized(), check.Equals, true)
+	}
+}
+
 func (t *Tree) dot(label string) string {
 	if t == nil {
 		return ""
@@ -882,6 +3448,43 @@ func BenchmarkGet(b *testing.B) {
 	}
 }
 
+func BenchmarkAnyOverlap(b *testing.B) {
+	b.StopTimer()
+	var (
+		t      = &Tree{}
+		length = compInt(10)
+		N      = compInt(b.N)
+	)
+	for i := compInt(0); i < N; i++ {
+		s := N - i
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(s)}, false)
+	}
+	b.StartTimer()
+	for i := compInt(0); i < N; i++ {
+		s := N - i
+		t.AnyOverlap(&overlap{start: s, end: s + length})
+	}
+}
+
+func BenchmarkGetInto(b *testing.B) {
+	b.StopTimer()
+	var (
+		t      = &Tree{}
+		length = compInt(10)
+		N      = compInt(b.N)
+		buf    []Interface
+	)
+	for i := compInt(0); i < N; i++ {
+		s := N - i
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(s)}, false)
+	}
+	b.StartTimer()
+	for i := compInt(0); i < N; i++ {
+		s := N - i
+		buf = t.GetInto(&overlap{start: s, end: s + length}, buf[:0])
+	}
+}
+
 func BenchmarkMin(b *testing.B) {
 	b.StopTimer()
 	var (
@@ -0,0 +1,44 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"encoding/json"
+)
+
+// MarshalJSON implements the json.Marshaler interface. The stored intervals are written as
+// a JSON array in sort order. The concrete types of the stored intervals must be marshalable
+// by the encoding/json package.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	elems := t.Slice()
+	return json.Marshal(elems)
+}
+
+// UnmarshalJSONFunc decodes a JSON array of intervals, as written by MarshalJSON, into the
+// receiver. Since the concrete type of the intervals is not known to the Tree, each array
+// element is passed to decode to construct the Interface to insert. Any intervals held by
+// the receiver are discarded and the Tree is rebuilt from the decoded intervals in O(n) time
+// when they are in sort order, keeping the receiver's node pool, hooks and node layout.
+func (t *Tree) UnmarshalJSONFunc(b []byte, decode func(json.RawMessage) (Interface, error)) error {
+	err := t.writable()
+	if err != nil {
+		return err
+	}
+	var raw []json.RawMessage
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	elems := make([]Interface, 0, len(raw))
+	for _, r := range raw {
+		e, err := decode(r)
+		if err != nil {
+			return err
+		}
+		elems = append(elems, e)
+	}
+	return t.load(elems)
+}
@@ -0,0 +1,56 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"encoding/json"
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+func (s *S) TestJSON(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = &Tree{}
+		length     = compInt(10)
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		t.Insert(&exported{S: s, E: s + length, Id: uintptr(i)}, false)
+	}
+
+	b, err := json.Marshal(t)
+	c.Assert(err, check.Equals, nil)
+	decode := func(r json.RawMessage) (Interface, error) {
+		var e exported
+		err := json.Unmarshal(r, &e)
+		return &e, err
+	}
+	nt := NewWithPool()
+	nt.Insert(&exported{S: -10, E: -5, Id: uintptr(count)}, false)
+	err = nt.UnmarshalJSONFunc(b, decode)
+	c.Assert(err, check.Equals, nil)
+	c.Check(nt.env.pool, check.NotNil)
+
+	c.Check(nt.Len(), check.Equals, t.Len())
+	c.Check(nt.Validate(), check.Equals, nil)
+	for s := compInt(-length); s <= compInt(max)+length; s++ {
+		q := &exported{S: s, E: s + 1}
+		c.Check(nt.Get(q), check.DeepEquals, t.Get(q))
+	}
+
+	err = nt.UnmarshalJSONFunc([]byte(`{}`), nil)
+	c.Check(err, check.Not(check.Equals), nil)
+	c.Check(nt.Len(), check.Equals, t.Len())
+
+	u := &Tree{}
+	err = u.UnmarshalJSONFunc([]byte(`[{"S":5,"E":6,"Id":1},{"S":1,"E":2,"Id":2},{"S":5,"E":7,"Id":1}]`), decode)
+	c.Assert(err, check.Equals, nil)
+	c.Check(u.Validate(), check.Equals, nil)
+	c.Check(u.Slice(), check.DeepEquals, []Interface{&exported{S: 1, E: 2, Id: 2}, &exported{S: 5, E: 7, Id: 1}})
+	err = u.UnmarshalJSONFunc([]byte(`[{"S":5,"E":1,"Id":1}]`), decode)
+	c.Check(err, check.Equals, ErrInvertedRange)
+	c.Check(u.Len(), check.Equals, 2)
+}
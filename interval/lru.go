@@ -0,0 +1,130 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"container/list"
+)
+
+// An LRUTree is an interval tree with a capacity, beyond which the least recently matched
+// intervals are evicted. An interval is matched when it is inserted and when it is returned
+// by Get or passed to the Operation of DoMatching. Intervals are otherwise stored and queried
+// as by a Tree.
+type LRUTree struct {
+	t        Tree
+	capacity int
+	recency  *list.List // Elements hold *lruEntry, most recently matched first.
+}
+
+// NewLRUTree returns a new empty LRUTree with the given capacity. A capacity less than one
+// leaves the LRUTree unbounded.
+func NewLRUTree(capacity int) *LRUTree {
+	return &LRUTree{capacity: capacity, recency: list.New()}
+}
+
+// lruEntry is an Interface stored in an LRUTree with its position in the recency list.
+type lruEntry struct {
+	Interface
+	elem *list.Element
+}
+
+// unentry returns an Operation that marks the stored intervals as matched and calls fn with
+// the original inserted intervals.
+func (t *LRUTree) unentry(fn Operation) Operation {
+	return func(e Interface) (done bool) {
+		l := e.(*lruEntry)
+		t.recency.MoveToFront(l.elem)
+		return fn(l.Interface)
+	}
+}
+
+// Len returns the number of intervals stored in the LRUTree.
+func (t *LRUTree) Len() int { return t.t.Len() }
+
+// Capacity returns the capacity of the LRUTree.
+func (t *LRUTree) Capacity() int { return t.capacity }
+
+// SetCapacity sets the capacity of the LRUTree, immediately evicting the least recently
+// matched intervals until the number of stored intervals is within the new capacity. A
+// capacity less than one leaves the LRUTree unbounded.
+func (t *LRUTree) SetCapacity(n int) {
+	t.capacity = n
+	t.evict(false)
+}
+
+// evict deletes the least recently matched intervals until the LRUTree is within capacity.
+func (t *LRUTree) evict(fast bool) {
+	for t.capacity > 0 && t.t.Len() > t.capacity {
+		l := t.recency.Remove(t.recency.Back()).(*lruEntry)
+		t.t.Delete(l, fast)
+	}
+}
+
+// Insert inserts the Interface e into the LRUTree as the most recently matched interval,
+// replacing any stored interval with the same start and ID values. If the LRUTree then
+// exceeds its capacity, the least recently matched intervals are evicted. The fast parameter
+// has the same meaning as for Tree.Insert.
+func (t *LRUTree) Insert(e Interface, fast bool) error {
+	if e == nil {
+		return ErrNilOverlapper
+	}
+	if t.recency == nil {
+		t.recency = list.New()
+	}
+	var old *lruEntry
+	if n := t.t.Root.search(e.Start(), e.ID()); n != nil {
+		old = n.Elem.(*lruEntry)
+	}
+	l := &lruEntry{Interface: e}
+	err := t.t.Insert(l, fast)
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		t.recency.Remove(old.elem)
+	}
+	l.elem = t.recency.PushFront(l)
+	t.evict(fast)
+	return nil
+}
+
+// Delete deletes the interval with the start and ID values of e if it is stored in the
+// LRUTree. The fast parameter has the same meaning as for Tree.Delete.
+func (t *LRUTree) Delete(e Interface, fast bool) error {
+	if e == nil {
+		return ErrNilOverlapper
+	}
+	n := t.t.Root.search(e.Start(), e.ID())
+	if n == nil {
+		return nil
+	}
+	t.recency.Remove(n.Elem.(*lruEntry).elem)
+	return t.t.Delete(n.Elem, fast)
+}
+
+// AdjustRanges fixes range fields for all Nodes in the LRUTree. This must be called before
+// Get or DoMatching is used if fast insertion or deletion has been performed.
+func (t *LRUTree) AdjustRanges() { t.t.AdjustRanges() }
+
+// Get returns a slice of Interfaces that overlap q in the LRUTree according to q.Overlap(),
+// marking each as matched.
+func (t *LRUTree) Get(q Overlapper) (o []Interface) {
+	t.DoMatching(func(e Interface) (done bool) { o = append(o, e); return }, q)
+	return
+}
+
+// DoMatching performs fn on all intervals stored in the LRUTree that match q according to
+// q.Overlap(), marking each as matched before fn is called. A boolean is returned indicating
+// whether the traversal was interrupted by an Operation returning true.
+func (t *LRUTree) DoMatching(fn Operation, q Overlapper) bool {
+	return t.t.DoMatching(t.unentry(fn), q)
+}
+
+// Do performs fn on all intervals stored in the LRUTree in sort order without marking them
+// as matched. A boolean is returned indicating whether the traversal was interrupted by an
+// Operation returning true.
+func (t *LRUTree) Do(fn Operation) bool {
+	return t.t.Do(func(e Interface) (done bool) { return fn(e.(*lruEntry).Interface) })
+}
@@ -0,0 +1,51 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+)
+
+func (s *S) TestLRUTree(c *check.C) {
+	t := NewLRUTree(3)
+	var ivs []*overlap
+	for i := compInt(0); i < 3; i++ {
+		ivs = append(ivs, &overlap{start: i * 10, end: i*10 + 5, id: uintptr(i)})
+		c.Check(t.Insert(ivs[i], false), check.Equals, nil)
+	}
+	c.Check(t.Len(), check.Equals, 3)
+
+	// Match the oldest interval so that the second is evicted next.
+	c.Check(t.Get(&overlap{start: 0, end: 1}), check.DeepEquals, []Interface{ivs[0]})
+	ivs = append(ivs, &overlap{start: 30, end: 35, id: 3})
+	c.Check(t.Insert(ivs[3], false), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 3)
+	c.Check(t.Get(&overlap{start: 10, end: 15}), check.HasLen, 0)
+
+	// Replacing an interval marks it as matched without changing the count.
+	re := &overlap{start: 20, end: 26, id: 2}
+	c.Check(t.Insert(re, false), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 3)
+
+	t.SetCapacity(2)
+	c.Check(t.Capacity(), check.Equals, 2)
+	var got []Interface
+	t.Do(func(e Interface) (done bool) { got = append(got, e); return })
+	c.Check(got, check.DeepEquals, []Interface{re, ivs[3]})
+	c.Check(t.t.Validate(), check.Equals, nil)
+
+	c.Check(t.Delete(ivs[3], false), check.Equals, nil)
+	c.Check(t.Delete(ivs[3], false), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 1)
+	c.Check(t.recency.Len(), check.Equals, 1)
+
+	t.SetCapacity(0)
+	for i := 0; i < 100; i++ {
+		t.Insert(&overlap{start: compInt(i), end: compInt(i + 1), id: uintptr(i + 10)}, false)
+	}
+	c.Check(t.Len(), check.Equals, 101)
+	c.Check(t.recency.Len(), check.Equals, 101)
+	c.Check((&LRUTree{}).Insert(nil, false), check.Equals, ErrNilOverlapper)
+}
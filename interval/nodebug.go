@@ -0,0 +1,11 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !interval_debug
+
+package interval
+
+// debug enables checks of tree depth that detect inconsistent Comparable implementations
+// and checks for Mutable values that alias inserted intervals.
+const debug = false
@@ -0,0 +1,154 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// An OverlapMode specifies how the end points of intervals are treated when the Tree
+// determines overlap without using an Overlapper.
+type OverlapMode int
+
+const (
+	// HalfOpen treats intervals as [start, end), so intervals that only share an end
+	// point do not overlap.
+	HalfOpen OverlapMode = iota
+	// Closed treats intervals as [start, end], so intervals that share an end point
+	// overlap.
+	Closed
+	// Touching selects intervals that share an end point with the query but do not
+	// overlap it when treated as half-open.
+	Touching
+)
+
+// overlaps returns whether a and b overlap under the mode.
+func (m OverlapMode) overlaps(a, b Range) bool {
+	switch m {
+	case HalfOpen:
+		return Overlaps(a.Start(), a.End(), b.Start(), b.End(), true) == 0
+	case Closed:
+		return Overlaps(a.Start(), a.End(), b.Start(), b.End(), false) == 0
+	case Touching:
+		return a.Start().Compare(b.End()) == 0 || b.Start().Compare(a.End()) == 0
+	}
+	panic("interval: invalid overlap mode")
+}
+
+// Overlaps compares the interval a, from aMin to aMax, with the interval b, from bMin to bMax,
+// according to Compare. It returns a negative value if a lies wholly before b, a positive
+// value if a lies wholly after b and zero if a and b overlap. If halfOpen is true the
+// intervals are treated as [min, max), so intervals that only share an end point do not
+// overlap, otherwise they are treated as [min, max]. Overlaps may be used to implement the
+// Overlap method of an Interface:
+//
+//	func (i T) Overlap(b interval.Range) bool {
+//		return interval.Overlaps(i.Start(), i.End(), b.Start(), b.End(), true) == 0
+//	}
+func Overlaps(aMin, aMax, bMin, bMax Comparable, halfOpen bool) int {
+	if halfOpen {
+		switch {
+		case aMax.Compare(bMin) <= 0:
+			return -1
+		case bMax.Compare(aMin) <= 0:
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case aMax.Compare(bMin) < 0:
+		return -1
+	case bMax.Compare(aMin) < 0:
+		return 1
+	}
+	return 0
+}
+
+// Contains returns whether the point p lies within the interval from outerMin to outerMax
+// according to Compare. If halfOpen is true the interval is treated as [outerMin, outerMax),
+// otherwise it is treated as [outerMin, outerMax].
+func Contains(outerMin, outerMax, p Comparable, halfOpen bool) bool {
+	if outerMin.Compare(p) > 0 {
+		return false
+	}
+	if halfOpen {
+		return p.Compare(outerMax) < 0
+	}
+	return p.Compare(outerMax) <= 0
+}
+
+// GetMode returns a slice of Interfaces stored in the Tree that overlap q in sort order.
+// Overlap is determined by comparison of the start and end values of q and the stored
+// intervals according to mode rather than by calling an Overlap method, so the same stored
+// intervals can be queried under different end point conventions. If mode is not a valid
+// OverlapMode, ErrInvalidMode is returned, and if q is nil, ErrNilOverlapper is returned.
+func (t *Tree) GetMode(q Range, mode OverlapMode) ([]Interface, error) {
+	if mode < HalfOpen || mode > Touching {
+		return nil, ErrInvalidMode
+	}
+	if q == nil {
+		return nil, ErrNilOverlapper
+	}
+	var o []Interface
+	t.Root.doMatchMode(func(e Interface) (done bool) { o = append(o, e); return }, q, mode)
+	return o, nil
+}
+
+func (n *Node) doMatchMode(fn Operation, q Range, mode OverlapMode) (done bool) {
+	// Every mode selects a subset of the intervals that overlap q when treated as closed,
+	// so subtrees are pruned using closed overlap of their ranges.
+	if n == nil || !Closed.overlaps(q, n.Range) {
+		return false
+	}
+	if n.Left.doMatchMode(fn, q, mode) {
+		return true
+	}
+	if mode.overlaps(q, n.Elem) && n.each(fn) {
+		return true
+	}
+	return n.Right.doMatchMode(fn, q, mode)
+}
+
+// A TouchOverlapper is an Overlapper that can distinguish ranges it only touches, sharing
+// an end point with no other overlap, from ranges it strictly overlaps. The Overlap method
+// of a TouchOverlapper must return true for ranges it touches.
+type TouchOverlapper interface {
+	Overlapper
+	// Touch returns whether the receiver touches, but does not strictly overlap, the
+	// parameter.
+	Touch(Range) bool
+}
+
+// GetStrict returns a slice of Interfaces stored in the Tree that strictly overlap q, in sort
+// order. Stored intervals for which q.Overlap() is true are excluded if q is a TouchOverlapper
+// and q.Touch() is true. If q is not a TouchOverlapper, GetStrict is equivalent to Get.
+func (t *Tree) GetStrict(q Overlapper) []Interface {
+	tq, ok := q.(TouchOverlapper)
+	if !ok {
+		return t.Get(q)
+	}
+	var o []Interface
+	t.DoMatching(func(e Interface) (done bool) {
+		if !tq.Touch(e) {
+			o = append(o, e)
+		}
+		return
+	}, q)
+	return o
+}
+
+// GetTouching returns a slice of Interfaces stored in the Tree that touch but do not strictly
+// overlap q according to q.Touch(), in sort order. If q is not a TouchOverlapper, no stored
+// interval can be distinguished as touching q and GetTouching returns nil.
+func (t *Tree) GetTouching(q Overlapper) []Interface {
+	tq, ok := q.(TouchOverlapper)
+	if !ok {
+		return nil
+	}
+	var o []Interface
+	t.DoMatching(func(e Interface) (done bool) {
+		if tq.Touch(e) {
+			o = append(o, e)
+		}
+		return
+	}, q)
+	return o
+}
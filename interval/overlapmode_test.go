@@ -0,0 +1,110 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+// getMode returns the result of t.GetMode, failing c if an error is returned.
+func getMode(c *check.C, t *Tree, q Range, mode OverlapMode) []Interface {
+	o, err := t.GetMode(q, mode)
+	c.Assert(err, check.Equals, nil)
+	return o
+}
+
+func (s *S) TestGetMode(c *check.C) {
+	t := &Tree{}
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + compInt(rand.Intn(10)), id: uintptr(i)}, false)
+	}
+	for _, q := range []*overlap{{start: 100, end: 110}, {start: 500, end: 500}, {start: -10, end: 0}, {start: 0, end: 1010}} {
+		for _, mode := range []OverlapMode{HalfOpen, Closed, Touching} {
+			var want []Interface
+			t.Do(func(e Interface) (done bool) {
+				o := e.(*overlap)
+				var ok bool
+				switch mode {
+				case HalfOpen:
+					ok = o.start < q.end && q.start < o.end
+				case Closed:
+					ok = o.start <= q.end && q.start <= o.end
+				case Touching:
+					ok = o.start == q.end || q.start == o.end
+				}
+				if ok {
+					want = append(want, e)
+				}
+				return
+			})
+			c.Check(getMode(c, t, q, mode), check.DeepEquals, want, check.Commentf("q=%v mode=%d", q, mode))
+		}
+	}
+	c.Check(getMode(c, t, &overlap{start: 100, end: 110}, HalfOpen), check.DeepEquals, t.Get(&overlap{start: 100, end: 110}))
+	_, err := t.GetMode(&overlap{}, Touching+1)
+	c.Check(err, check.Equals, ErrInvalidMode)
+	_, err = t.GetMode(nil, HalfOpen)
+	c.Check(err, check.Equals, ErrNilOverlapper)
+}
+
+// touchQuery is a closed query range that reports stored intervals sharing only an end point
+// with it as touching.
+type touchQuery struct{ start, end compInt }
+
+func (q touchQuery) Overlap(b Range) bool {
+	return q.start <= b.End().(compInt) && b.Start().(compInt) <= q.end
+}
+func (q touchQuery) Touch(b Range) bool {
+	return q.start == b.End().(compInt) || b.Start().(compInt) == q.end
+}
+
+func (s *S) TestGetStrictTouching(c *check.C) {
+	t := &Tree{}
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		t.Insert(&overlap{start: s, end: s + 1 + compInt(rand.Intn(10)), id: uintptr(i)}, false)
+	}
+	for _, q := range []touchQuery{{100, 110}, {500, 501}, {-10, 0}, {0, 1020}} {
+		h := &overlap{start: q.start, end: q.end}
+		c.Check(t.GetStrict(q), check.DeepEquals, getMode(c, t, h, HalfOpen), check.Commentf("q=%v", q))
+		c.Check(t.GetTouching(q), check.DeepEquals, getMode(c, t, h, Touching), check.Commentf("q=%v", q))
+		c.Check(len(t.GetStrict(q))+len(t.GetTouching(q)), check.Equals, len(t.Get(q)))
+	}
+	q := &overlap{start: 100, end: 110}
+	c.Check(t.GetStrict(q), check.DeepEquals, t.Get(q))
+	c.Check(t.GetTouching(q), check.HasLen, 0)
+}
+
+func (s *S) TestOverlapsContains(c *check.C) {
+	for _, test := range []struct {
+		a, b     [2]compInt
+		halfOpen bool
+		want     int
+	}{
+		{a: [2]compInt{0, 5}, b: [2]compInt{5, 10}, halfOpen: true, want: -1},
+		{a: [2]compInt{0, 5}, b: [2]compInt{5, 10}, halfOpen: false, want: 0},
+		{a: [2]compInt{5, 10}, b: [2]compInt{0, 5}, halfOpen: true, want: 1},
+		{a: [2]compInt{5, 10}, b: [2]compInt{0, 5}, halfOpen: false, want: 0},
+		{a: [2]compInt{0, 4}, b: [2]compInt{5, 10}, halfOpen: false, want: -1},
+		{a: [2]compInt{11, 12}, b: [2]compInt{5, 10}, halfOpen: false, want: 1},
+		{a: [2]compInt{2, 8}, b: [2]compInt{5, 10}, halfOpen: true, want: 0},
+		{a: [2]compInt{6, 7}, b: [2]compInt{5, 10}, halfOpen: true, want: 0},
+		{a: [2]compInt{5, 5}, b: [2]compInt{5, 10}, halfOpen: true, want: -1},
+		{a: [2]compInt{5, 5}, b: [2]compInt{5, 10}, halfOpen: false, want: 0},
+	} {
+		c.Check(Overlaps(test.a[0], test.a[1], test.b[0], test.b[1], test.halfOpen), check.Equals, test.want, check.Commentf("%v %v halfOpen=%t", test.a, test.b, test.halfOpen))
+	}
+	for _, test := range []struct {
+		p            compInt
+		open, closed bool
+	}{
+		{p: -1}, {p: 0, open: true, closed: true}, {p: 4, open: true, closed: true}, {p: 5, closed: true}, {p: 6},
+	} {
+		c.Check(Contains(compInt(0), compInt(5), test.p, true), check.Equals, test.open, check.Commentf("p=%d", test.p))
+		c.Check(Contains(compInt(0), compInt(5), test.p, false), check.Equals, test.closed, check.Commentf("p=%d", test.p))
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"sync"
+)
+
+// NewWithPool returns an empty Tree that obtains new nodes from, and returns deleted nodes
+// to, a pool owned by the Tree. Nodes hold their ranges within the node allocation, as for
+// NewCompact, so a recycled node is reused with its range and inserting into the Tree after
+// a deletion need not allocate. This reduces allocation in workloads where insertions and
+// deletions are interleaved. Apart from allocation behavior, the returned Tree behaves
+// identically to a zero Tree. Nodes released by operations that rebuild or empty the Tree,
+// such as Clear, Filter, DeleteRange, Coalesce and Rebalance, are also returned to the pool,
+// so for a pooled Tree Clear takes O(n) time rather than O(1). Trees returned by Map, Trim
+// and Split do not use a pool.
+func NewWithPool() *Tree {
+	return &Tree{env: &treeEnv{pool: &sync.Pool{}}}
+}
+
+// treeEnv holds the state of a Tree used by node operations: a pool of Nodes and the hooks
+// called during rebalancing. A nil *treeEnv, or one with a nil pool, allocates new nodes and
+// discards released nodes. Pooled nodes are compact nodes. A nil *treeEnv calls no hooks.
+type treeEnv struct {
+	pool     *sync.Pool
+	hooks    Hooks
+	compact  bool // Whether nodes hold their ranges in the node allocation.
+	weighted bool // Whether nodes hold the sum of the weights of their subtree.
+	shared   bool // Whether nodes may hold Shared intervals.
+}
+
+// get returns a Node holding e with its range set from e.
+func (p *treeEnv) get(e Interface) *Node {
+	switch {
+	case p == nil:
+		return &Node{Elem: e, Range: e.NewMutable(), Size: 1}
+	case p.pool != nil:
+		n, _ := p.pool.Get().(*Node)
+		if n == nil {
+			return newCompactNode(e)
+		}
+		n.Elem, n.Size = e, 1
+		n.Range.SetStart(e.Start())
+		n.Range.SetEnd(e.End())
+		return n
+	case p.compact:
+		return newCompactNode(e)
+	case p.weighted:
+		return &Node{Elem: e, Range: e.NewMutable(), Size: 1, weighted: true, Weight: weightOf(e)}
+	}
+	return &Node{Elem: e, Range: e.NewMutable(), Size: 1}
+}
+
+// put zeroes n and returns it to the pool if it is a compact node.
+func (p *treeEnv) put(n *Node) {
+	if p == nil || p.pool == nil {
+		return
+	}
+	r, ok := n.Range.(*compactRange)
+	if !ok {
+		return
+	}
+	*r = compactRange{}
+	*n = Node{Range: r}
+	p.pool.Put(n)
+}
+
+// putAll returns the nodes of the subtree rooted at n to the pool.
+func (p *treeEnv) putAll(n *Node) {
+	if p == nil || p.pool == nil || n == nil {
+		return
+	}
+	l, r := n.Left, n.Right
+	p.put(n)
+	p.putAll(l)
+	p.putAll(r)
+}
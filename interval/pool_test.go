@@ -0,0 +1,108 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"testing"
+)
+
+func (s *S) TestNewWithPool(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = NewWithPool()
+		u          = &Tree{}
+		length     = compInt(10)
+		elems      []Interface
+	)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		e := &overlap{start: s, end: s + length, id: uintptr(i)}
+		elems = append(elems, e)
+		t.Insert(e, false)
+		u.Insert(e, false)
+	}
+	for i, e := range elems {
+		switch i % 4 {
+		case 0:
+			t.Delete(e, false)
+			u.Delete(e, false)
+		case 1:
+			t.DeleteMin(false)
+			u.DeleteMin(false)
+		case 2:
+			t.DeleteMax(false)
+			u.DeleteMax(false)
+		}
+		if i%2 == 0 {
+			ne := &overlap{start: e.(*overlap).start + 1, end: e.(*overlap).end + 1, id: uintptr(count + i)}
+			t.Insert(ne, false)
+			u.Insert(ne, false)
+		}
+		c.Assert(t.Len(), check.Equals, u.Len())
+	}
+	c.Check(t.Validate(), check.Equals, nil)
+	for s := compInt(-length); s <= compInt(max)+length; s++ {
+		q := &overlap{start: s, end: s + 1}
+		c.Check(t.Get(q), check.DeepEquals, u.Get(q))
+	}
+}
+
+func (s *S) TestPoolRebuild(c *check.C) {
+	var (
+		t = NewWithPool()
+		u = &Tree{}
+	)
+	for i := 0; i < 1000; i++ {
+		s := compInt(rand.Intn(1000))
+		e := &overlap{start: s, end: s + 10, id: uintptr(i)}
+		t.Insert(e, false)
+		u.Insert(e, false)
+	}
+	odd := func(e Interface) bool { return e.ID()%2 == 1 }
+	c.Check(t.Filter(odd), check.Equals, u.Filter(odd))
+	t.Rebalance()
+	n, err := t.DeleteRange(compInt(100), compInt(200))
+	c.Check(err, check.Equals, nil)
+	m, _ := u.DeleteRange(compInt(100), compInt(200))
+	c.Check(n, check.Equals, m)
+	for i := 1000; i < 1500; i++ {
+		s := compInt(rand.Intn(1000))
+		e := &overlap{start: s, end: s + 10, id: uintptr(i)}
+		t.Insert(e, false)
+		u.Insert(e, false)
+	}
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Slice(), check.DeepEquals, u.Slice())
+
+	t.Clear()
+	c.Check(t.Len(), check.Equals, 0)
+	if n, _ := t.env.pool.Get().(*Node); n != nil {
+		c.Check(n.Elem, check.Equals, nil)
+		c.Check(n.Left == nil && n.Right == nil, check.Equals, true)
+	}
+}
+
+func benchmarkInsertDelete(b *testing.B, t *Tree) {
+	const window = 1000
+	length := compInt(10)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := compInt(i)
+		t.Insert(&overlap{start: s, end: s + length, id: uintptr(i)}, false)
+		if i >= window {
+			t.DeleteMin(false)
+		}
+	}
+}
+
+func BenchmarkInsertDelete(b *testing.B) {
+	benchmarkInsertDelete(b, &Tree{})
+}
+
+func BenchmarkInsertDeletePool(b *testing.B) {
+	benchmarkInsertDelete(b, NewWithPool())
+}
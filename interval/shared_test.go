@@ -0,0 +1,185 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+)
+
+func (t *Tree) nodes() int {
+	var count func(*Node) int
+	count = func(n *Node) int {
+		if n == nil {
+			return 0
+		}
+		return 1 + count(n.Left) + count(n.Right)
+	}
+	return count(t.Root)
+}
+
+func (s *S) TestInsertShared(c *check.C) {
+	var (
+		t        = &Tree{}
+		equalKey = func(a, b Interface) bool {
+			return a.Start().Compare(b.Start()) == 0 && a.End().Compare(b.End()) == 0
+		}
+		id uintptr
+	)
+	for k := 0; k < 3; k++ {
+		for i := compInt(0); i < 100; i++ {
+			c.Check(t.InsertShared(&overlap{start: i, end: i + 5, id: id}, equalKey, false), check.Equals, nil)
+			id++
+		}
+	}
+	c.Check(t.InsertShared(&overlap{start: 1, end: 0}, equalKey, false), check.Equals, ErrInvertedRange)
+	c.Check(t.InsertShared(&overlap{start: 7, end: 12, id: 107}, equalKey, false), check.Equals, ErrDuplicate)
+	c.Check(t.Len(), check.Equals, 300)
+	c.Check(t.nodes(), check.Equals, 100)
+	c.Check(t.Validate(), check.Equals, nil)
+
+	all := t.Slice()
+	c.Assert(len(all), check.Equals, 300)
+	for i, e := range all {
+		o := e.(*overlap)
+		c.Check(o.start, check.Equals, compInt(i/3))
+		c.Check(o.id, check.Equals, uintptr(i/3+i%3*100))
+		got, err := t.Select(i)
+		c.Check(err, check.Equals, nil)
+		c.Check(got, check.Equals, e)
+		c.Check(t.Rank(e), check.Equals, i)
+	}
+	rev := t.SliceReverse()
+	for i := range rev {
+		c.Check(rev[i], check.Equals, all[len(all)-1-i])
+	}
+
+	var fwd []Interface
+	for cur := t.Cursor(); ; {
+		e, ok := cur.Next()
+		if !ok {
+			break
+		}
+		fwd = append(fwd, e)
+	}
+	c.Check(fwd, check.DeepEquals, all)
+	var bwd []Interface
+	for cur := t.ReverseCursor(); ; {
+		e, ok := cur.Prev()
+		if !ok {
+			break
+		}
+		bwd = append(bwd, e)
+	}
+	c.Check(bwd, check.DeepEquals, rev)
+
+	q := &overlap{start: 10, end: 12}
+	got := t.Get(q)
+	c.Check(len(got), check.Equals, 3*6)
+	var matched []Interface
+	for cur := t.MatchCursor(q); ; {
+		e, ok := cur.Next()
+		if !ok {
+			break
+		}
+		matched = append(matched, e)
+	}
+	c.Check(matched, check.DeepEquals, got)
+
+	same := func(a, b Interface) bool { return a.ID() == b.ID() }
+	ok, err := t.DeleteElem(&overlap{start: 50, end: 55, id: 150}, same, false)
+	c.Check(ok, check.Equals, true)
+	c.Check(err, check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 299)
+	c.Check(t.Validate(), check.Equals, nil)
+
+	c.Check(t.Delete(&overlap{start: 60, end: 65, id: 60}, false), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 298)
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Get(&overlap{start: 60, end: 61}), check.HasLen, 3*5-1)
+
+	t.DeleteMin(false)
+	c.Check(t.Len(), check.Equals, 297)
+	c.Check(t.Validate(), check.Equals, nil)
+
+	want := t.Slice()
+	u := t.Clone()
+	c.Check(u.Validate(), check.Equals, nil)
+	c.Check(u.Slice(), check.DeepEquals, want)
+	t.Rebalance()
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(t.Len(), check.Equals, 297)
+	c.Check(t.nodes(), check.Equals, 297)
+}
+
+func (s *S) TestSharedOrder(c *check.C) {
+	var (
+		t        = &Tree{}
+		equalKey = func(a, b Interface) bool {
+			return a.Start().Compare(b.Start()) == 0 && a.End().Compare(b.End()) == 0
+		}
+	)
+	for _, id := range []uintptr{10, 20, 30} {
+		c.Check(t.InsertShared(&overlap{start: 1, end: 5, id: id}, equalKey, false), check.Equals, nil)
+	}
+	c.Check(t.nodes(), check.Equals, 1)
+
+	// An interval that would not be adjacent to the run is given its own node.
+	c.Check(t.Insert(&overlap{start: 1, end: 4, id: 40}, false), check.Equals, nil)
+	c.Check(t.InsertShared(&overlap{start: 1, end: 5, id: 50}, equalKey, false), check.Equals, nil)
+	c.Check(t.InsertShared(&overlap{start: 1, end: 5, id: 5}, equalKey, false), check.Equals, nil)
+	c.Check(t.InsertShared(&overlap{start: 1, end: 5, id: 25}, equalKey, false), check.Equals, nil)
+	c.Check(t.nodes(), check.Equals, 3)
+	c.Check(t.Validate(), check.Equals, nil)
+
+	// Inserting into a run splits it around the new interval.
+	c.Check(t.Insert(&overlap{start: 1, end: 3, id: 15}, false), check.Equals, nil)
+	c.Check(t.Validate(), check.Equals, nil)
+
+	ids := func(s []Interface) []uintptr {
+		var o []uintptr
+		for _, e := range s {
+			o = append(o, e.ID())
+		}
+		return o
+	}
+	c.Check(ids(t.Slice()), check.DeepEquals, []uintptr{5, 10, 15, 20, 25, 30, 40, 50})
+	for i, e := range t.Slice() {
+		c.Check(t.Rank(e), check.Equals, i)
+	}
+
+	idOf := func(e Interface, err error) uintptr {
+		c.Assert(err, check.Equals, nil)
+		if e == nil {
+			return 0
+		}
+		return e.ID()
+	}
+	all := func(Interface) bool { return true }
+	for _, test := range []struct {
+		id                      uintptr
+		floor, ceil, pred, succ uintptr
+	}{
+		{id: 1, floor: 0, ceil: 5, pred: 0, succ: 5},
+		{id: 7, floor: 5, ceil: 10, pred: 5, succ: 10},
+		{id: 12, floor: 10, ceil: 15, pred: 10, succ: 15},
+		{id: 20, floor: 20, ceil: 20, pred: 15, succ: 25},
+		{id: 22, floor: 20, ceil: 25, pred: 20, succ: 25},
+		{id: 30, floor: 30, ceil: 30, pred: 25, succ: 40},
+		{id: 60, floor: 50, ceil: 0, pred: 50, succ: 0},
+	} {
+		q := &overlap{start: 1, end: 5, id: test.id}
+		c.Check(idOf(t.Floor(q)), check.Equals, test.floor, check.Commentf("id %d", test.id))
+		c.Check(idOf(t.Ceil(q)), check.Equals, test.ceil, check.Commentf("id %d", test.id))
+		c.Check(idOf(t.Predecessor(q)), check.Equals, test.pred, check.Commentf("id %d", test.id))
+		c.Check(idOf(t.Successor(q)), check.Equals, test.succ, check.Commentf("id %d", test.id))
+		c.Check(idOf(t.FloorWhere(q, all)), check.Equals, test.floor, check.Commentf("id %d", test.id))
+		c.Check(idOf(t.CeilWhere(q, all)), check.Equals, test.ceil, check.Commentf("id %d", test.id))
+	}
+
+	c.Check(t.Delete(&overlap{start: 1, end: 5, id: 25}, false), check.Equals, nil)
+	t.DeleteMin(false)
+	c.Check(t.Validate(), check.Equals, nil)
+	c.Check(ids(t.Slice()), check.DeepEquals, []uintptr{10, 15, 20, 30, 40, 50})
+}
@@ -0,0 +1,64 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// TreeStats holds summary statistics describing the structure of a Tree.
+type TreeStats struct {
+	Count       int // Number of intervals stored.
+	Height      int // Number of nodes on the longest path from the root to a leaf.
+	BlackHeight int // Number of black nodes on each path from the root to a leaf.
+
+	// EstimatedBytes is the memory held by the Tree's nodes, including the
+	// nodes' ranges and the backing arrays of their Shared intervals. Ranges
+	// held within the node allocation, as by NewCompact and NewWithPool, are
+	// counted with the node, and a separately allocated Range is counted by the
+	// size of its dynamic value. The estimate does not include the memory held
+	// by the stored intervals, memory referenced by the range values, such as
+	// boxed Comparables, or allocator size-class rounding.
+	EstimatedBytes int
+}
+
+// Stats returns summary statistics for the Tree. Calculating the statistics takes O(n) time.
+func (t *Tree) Stats() TreeStats {
+	return TreeStats{
+		Count:          t.Count,
+		Height:         t.Height(),
+		BlackHeight:    t.BlackHeight(),
+		EstimatedBytes: t.Root.bytes(),
+	}
+}
+
+// bytes returns the memory held by the nodes of the subtree rooted at n.
+func (n *Node) bytes() int {
+	if n == nil {
+		return 0
+	}
+	var b int
+	if _, ok := n.Range.(*compactRange); ok {
+		b = int(unsafe.Sizeof(compactNode{}))
+	} else {
+		b = int(unsafe.Sizeof(Node{})) + rangeBytes(n.Range)
+	}
+	b += cap(n.Shared) * int(unsafe.Sizeof(Interface(nil)))
+	return b + n.Left.bytes() + n.Right.bytes()
+}
+
+// rangeBytes returns the size of the value allocated for the Range r. A Range held by
+// pointer is counted by the size of the value it points to.
+func rangeBytes(r Mutable) int {
+	if r == nil {
+		return 0
+	}
+	t := reflect.TypeOf(r)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return int(t.Size())
+}
@@ -0,0 +1,39 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"unsafe"
+)
+
+func (s *S) TestStats(c *check.C) {
+	t := &Tree{}
+	c.Check(t.Stats(), check.Equals, TreeStats{})
+	for i := compInt(0); i < 1000; i++ {
+		t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+	}
+	st := t.Stats()
+	c.Check(st.Count, check.Equals, 1000)
+	c.Check(st.Height, check.Equals, t.Height())
+	c.Check(st.BlackHeight, check.Equals, t.BlackHeight())
+	c.Check(st.BlackHeight > 0 && st.BlackHeight <= st.Height, check.Equals, true)
+	c.Check(st.EstimatedBytes, check.Equals, 1000*int(unsafe.Sizeof(Node{})+unsafe.Sizeof(overlap{})))
+
+	for _, t := range []*Tree{NewCompact(), NewWithPool()} {
+		for i := compInt(0); i < 1000; i++ {
+			t.Insert(&overlap{start: i, end: i + 5, id: uintptr(i)}, false)
+		}
+		c.Check(t.Stats().EstimatedBytes, check.Equals, 1000*int(unsafe.Sizeof(compactNode{})))
+	}
+
+	u := &Tree{}
+	equalKey := func(a, b Interface) bool { return a.Start().Compare(b.Start()) == 0 }
+	for id := uintptr(0); id < 3; id++ {
+		u.InsertShared(&overlap{start: 1, end: 2, id: id}, equalKey, false)
+	}
+	c.Assert(u.Root.Shared, check.HasLen, 2)
+	c.Check(u.Stats().EstimatedBytes, check.Equals, int(unsafe.Sizeof(Node{})+unsafe.Sizeof(overlap{}))+cap(u.Root.Shared)*int(unsafe.Sizeof(Interface(nil))))
+}
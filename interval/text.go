@@ -0,0 +1,30 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"bufio"
+	"io"
+)
+
+// WriteText writes the stored intervals of the Tree to w in sort order, one per line, as
+// the string returned by format followed by a newline. Writes to w are buffered and the
+// first write error is returned, after which no further intervals are formatted. The
+// coordinate convention of the output, for example tab-separated BED-like start and end
+// values, is determined entirely by format.
+func (t *Tree) WriteText(w io.Writer, format func(Interface) string) error {
+	bw := bufio.NewWriter(w)
+	err := t.DoErr(func(e Interface) error {
+		_, err := bw.WriteString(format(e))
+		if err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,39 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	"bytes"
+	"fmt"
+	check "launchpad.net/gocheck"
+)
+
+func (s *S) TestWriteText(c *check.C) {
+	var (
+		t      = &Tree{}
+		format = func(e Interface) string {
+			x := e.(*overlap)
+			return fmt.Sprintf("%d\t%d", x.start, x.end)
+		}
+	)
+	for i, iv := range []*overlap{{start: 5, end: 9}, {start: 1, end: 3}, {start: 5, end: 6}} {
+		iv.id = uintptr(i)
+		t.Insert(iv, false)
+	}
+
+	var buf bytes.Buffer
+	c.Check((&Tree{}).WriteText(&buf, format), check.Equals, nil)
+	c.Check(buf.Len(), check.Equals, 0)
+	c.Check(t.WriteText(&buf, format), check.Equals, nil)
+	c.Check(buf.String(), check.Equals, "1\t3\n5\t9\n5\t6\n")
+
+	c.Check(t.WriteText(failWriter{}, format), check.ErrorMatches, "write failed")
+	for i := compInt(3); i < 1000; i++ {
+		t.Insert(&overlap{start: i, end: i + 1, id: uintptr(i)}, false)
+	}
+	var n int
+	c.Check(t.WriteText(failWriter{}, func(e Interface) string { n++; return format(e) }), check.ErrorMatches, "write failed")
+	c.Check(n < t.Len(), check.Equals, true)
+}
@@ -0,0 +1,14 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+// NewWeighted returns an empty Tree that maintains in the Weight field of each Node the sum
+// of the weights of the Weighted intervals stored in the Node's subtree, so that
+// WeightOverlapping need not visit every overlapping interval. The Weight fields of nodes in
+// other trees are left at zero. Apart from this, the returned Tree behaves identically to a
+// zero Tree; trees returned by Map, Trim and Split do not maintain weights.
+func NewWeighted() *Tree {
+	return &Tree{env: &treeEnv{weighted: true}}
+}
@@ -0,0 +1,80 @@
+// Copyright ©2014 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interval
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+)
+
+// weightedOverlap is a Weighted interval.
+type weightedOverlap struct {
+	*overlap
+	w float64
+}
+
+func (o weightedOverlap) Weight() float64 { return o.w }
+
+func (t *Tree) isWeighted() bool {
+	var ok func(*Node) bool
+	ok = func(n *Node) bool {
+		if n == nil {
+			return true
+		}
+		return n.weighted && n.weight() == n.Left.weight()+n.Right.weight()+n.ownWeight() && ok(n.Left) && ok(n.Right)
+	}
+	return ok(t.Root)
+}
+
+func (s *S) TestWeightOverlapping(c *check.C) {
+	var (
+		count, max = 1000, 1000
+		t          = NewWeighted()
+		u          = &Tree{}
+		length     = compInt(10)
+		elems      []Interface
+	)
+	c.Check(t.WeightOverlapping(&overlap{start: 0, end: 1}), check.Equals, 0.)
+	for i := 0; i < count; i++ {
+		s := compInt(rand.Intn(max))
+		var e Interface = &overlap{start: s, end: s + length, id: uintptr(i)}
+		if i%10 != 0 {
+			e = weightedOverlap{e.(*overlap), float64(rand.Intn(10))}
+		}
+		elems = append(elems, e)
+		t.Insert(e, false)
+		u.Insert(e, false)
+	}
+	verify := func() {
+		c.Assert(t.isWeighted(), check.Equals, true)
+		for _, q := range []*overlap{{start: -1, end: 2000}, {start: 100, end: 200}, {start: 500, end: 501}, {start: 5000, end: 6000}} {
+			var want float64
+			for _, e := range t.Get(q) {
+				want += weightOf(e)
+			}
+			c.Check(t.WeightOverlapping(q), check.Equals, want, check.Commentf("q=%v", q))
+		}
+	}
+	verify()
+	for _, q := range []*overlap{{start: -1, end: 2000}, {start: 100, end: 200}} {
+		c.Check(u.WeightOverlapping(q), check.Equals, t.WeightOverlapping(q))
+	}
+
+	for _, e := range elems[:count/2] {
+		t.Delete(e, false)
+	}
+	verify()
+
+	for _, e := range elems[count/2:] {
+		if w, ok := e.(weightedOverlap); ok {
+			c.Check(t.Replace(e, weightedOverlap{w.overlap, w.w + 100}), check.Equals, nil)
+		}
+	}
+	verify()
+	t.DeleteMin(false)
+	t.DeleteMax(false)
+	verify()
+	c.Check(t.Clone().isWeighted(), check.Equals, true)
+}